@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Backend is an engine that runs functions written in some language.
+// Each backend gets its own provider attribute for the source code, named after the backend.
+type Backend interface {
+	// Name is the name of the provider attribute holding the source code, e.g. "go".
+	Name() string
+	// Description describes the source code expected in the provider attribute.
+	Description() string
+	// Load loads the source code, and returns the functions it exports.
+	// The settings are the value of the settings provider attribute, which may be null.
+	Load(source string, settings cty.Value, opts ConvertOptions) (Library, []*tfprotov6.Diagnostic)
+}
+
+// Validator is implemented by backends which can check source code without loading it.
+type Validator interface {
+	// Validate returns a diagnostic for each error found in the source code.
+	Validate(source string) []*tfprotov6.Diagnostic
+}
+
+// Library is source code loaded by a Backend.
+// Libraries holding resources, like a WebAssembly runtime, also implement io.Closer,
+// and are closed when the provider is configured again.
+type Library interface {
+	// Functions returns the signatures of the exported functions, by their Tofu-facing name.
+	Functions() map[string]*tfprotov6.Function
+	// Call invokes the function with the given name.
+	Call(name string, args []*tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, *tfprotov6.FunctionError)
+}
+
+// closeLibraries closes the libraries implementing io.Closer.
+// Errors are ignored, as nothing can be done about them once the libraries are replaced.
+func closeLibraries(libraries []Library) {
+	for _, library := range libraries {
+		if closer, ok := library.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+// FunctionLibrary is a Library of functions which each carry their own implementation.
+type FunctionLibrary map[string]*Function
+
+func (l FunctionLibrary) Functions() map[string]*tfprotov6.Function {
+	functions := make(map[string]*tfprotov6.Function, len(l))
+	for name, fn := range l {
+		functions[name] = &fn.Function
+	}
+	return functions
+}
+
+func (l FunctionLibrary) Call(name string, args []*tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, *tfprotov6.FunctionError) {
+	fn, ok := l[name]
+	if !ok {
+		return nil, &tfprotov6.FunctionError{
+			Text: "unknown function " + name,
+		}
+	}
+	return fn.Impl(args)
+}
+
+// signatureToTFFunction converts the signature of a function in a dynamically typed language,
+// like a table returned by Lua code or an entry of the manifest of a WebAssembly module,
+// e.g. { params = { "string" }, returns = "string", description = "Greets the given name." }.
+// The parameter and return types are Tofu type constraints.
+func signatureToTFFunction(signature any) (*tfprotov6.Function, error) {
+	fields, ok := signature.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("signature must be a table")
+	}
+
+	var parameters []*tfprotov6.FunctionParameter
+	params, _ := fields["params"].([]any)
+	for i, param := range params {
+		typeExpr, ok := param.(string)
+		if !ok {
+			return nil, fmt.Errorf("parameter %d: type must be a string", i+1)
+		}
+		paramType, err := ParseTypeExpr(typeExpr)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %d: %w", i+1, err)
+		}
+		parameters = append(parameters, &tfprotov6.FunctionParameter{
+			Name: fmt.Sprintf("arg%d", i+1),
+			Type: paramType,
+		})
+	}
+
+	returns, ok := fields["returns"].(string)
+	if !ok {
+		return nil, fmt.Errorf("signature must have a returns type")
+	}
+	returnType, err := ParseTypeExpr(returns)
+	if err != nil {
+		return nil, fmt.Errorf("returns: %w", err)
+	}
+
+	description, _ := fields["description"].(string)
+	return &tfprotov6.Function{
+		Parameters:  parameters,
+		Return:      &tfprotov6.FunctionReturn{Type: returnType},
+		Description: description,
+	}, nil
+}
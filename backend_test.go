@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// fakeBackend loads source code made of the space-separated names of functions,
+// which return their name followed by their string argument.
+// Sources containing "invalid" fail to load, and fail validation if the backend is a fakeValidator.
+type fakeBackend struct {
+	name string
+	// libraries are the libraries loaded so far.
+	libraries []*fakeLibrary
+}
+
+func (b *fakeBackend) Name() string {
+	return b.name
+}
+
+func (b *fakeBackend) Description() string {
+	return "Names of fake functions."
+}
+
+func (b *fakeBackend) Load(source string, settings cty.Value, opts ConvertOptions) (Library, []*tfprotov6.Diagnostic) {
+	library := &fakeLibrary{names: strings.Fields(source)}
+	b.libraries = append(b.libraries, library)
+	if strings.Contains(source, "invalid") {
+		return library, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid fake source",
+		}}
+	}
+	return library, nil
+}
+
+// fakeValidator is a fakeBackend which validates its source code.
+type fakeValidator struct {
+	fakeBackend
+}
+
+func (b *fakeValidator) Validate(source string) []*tfprotov6.Diagnostic {
+	if !strings.Contains(source, "invalid") {
+		return nil
+	}
+	return []*tfprotov6.Diagnostic{
+		&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid fake source",
+		},
+		&tfprotov6.Diagnostic{
+			Severity:  tfprotov6.DiagnosticSeverityWarning,
+			Summary:   "Fake warning",
+			Attribute: tftypes.NewAttributePath().WithAttributeName("settings"),
+		},
+	}
+}
+
+// fakeLibrary is a Library loaded by a fakeBackend, which records whether it was closed.
+type fakeLibrary struct {
+	names  []string
+	closed bool
+}
+
+func (l *fakeLibrary) Functions() map[string]*tfprotov6.Function {
+	functions := map[string]*tfprotov6.Function{}
+	for _, name := range l.names {
+		functions[name] = &tfprotov6.Function{
+			Parameters: []*tfprotov6.FunctionParameter{&tfprotov6.FunctionParameter{Name: "s", Type: tftypes.String}},
+			Return:     &tfprotov6.FunctionReturn{Type: tftypes.String},
+		}
+	}
+	return functions
+}
+
+func (l *fakeLibrary) Call(name string, args []*tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, *tfprotov6.FunctionError) {
+	arg, err := ProtoToCty(cty.String, args[0])
+	if err != nil {
+		return nil, &tfprotov6.FunctionError{Text: err.Error()}
+	}
+	result, err := CtyToProto(cty.String, cty.StringVal(name+" "+arg.AsString()))
+	if err != nil {
+		return nil, &tfprotov6.FunctionError{Text: err.Error()}
+	}
+	return result, nil
+}
+
+func (l *fakeLibrary) Close() error {
+	l.closed = true
+	return nil
+}
+
+// startFakeProvider returns a provider using only the given backends.
+func startFakeProvider(t *testing.T, backends ...Backend) *testProvider {
+	return &testProvider{t: t, ctx: context.Background(), server: NewFunctionProvider(backends...)}
+}
+
+func TestConfigureBackends(t *testing.T) {
+	p := startFakeProvider(t, &fakeBackend{name: "alpha"}, &fakeBackend{name: "beta"})
+	requireNoDiagnostics(t, p.configure(map[string]cty.Value{"alpha": cty.StringVal("one two"), "beta": cty.StringVal("three")}))
+
+	requireEqual(t, p.mustCall("one", cty.StringVal("a")), cty.StringVal("one a"))
+	requireEqual(t, p.mustCall("two", cty.StringVal("b")), cty.StringVal("two b"))
+	requireEqual(t, p.mustCall("three", cty.StringVal("c")), cty.StringVal("three c"))
+}
+
+func TestConfigureBackendDiagnostics(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]cty.Value
+		summary string
+		detail  string
+		path    *tftypes.AttributePath
+	}{
+		{
+			name:    "duplicate function",
+			config:  map[string]cty.Value{"alpha": cty.StringVal("one two"), "beta": cty.StringVal("two")},
+			summary: "Duplicate function",
+			detail:  "The function two is defined more than once.",
+		},
+		{
+			name:    "duplicate builtin function",
+			config:  map[string]cty.Value{"alpha": cty.StringVal("semver_compare")},
+			summary: "Duplicate function",
+			detail:  "The function semver_compare is defined more than once.",
+		},
+		{
+			name:    "load error",
+			config:  map[string]cty.Value{"beta": cty.StringVal("invalid")},
+			summary: "Invalid fake source",
+			path:    tftypes.NewAttributePath().WithAttributeName("beta"),
+		},
+		{
+			name:    "missing source code",
+			config:  map[string]cty.Value{},
+			summary: "Missing source code",
+			detail:  `At least one of the "alpha", "beta" attributes must be set.`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			alpha, beta := &fakeBackend{name: "alpha"}, &fakeBackend{name: "beta"}
+			diags := startFakeProvider(t, alpha, beta).configure(test.config)
+			requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, test.summary, test.detail)
+			if test.path != nil && !diags[0].Attribute.Equal(test.path) {
+				t.Errorf("got attribute %v, want %v", diags[0].Attribute, test.path)
+			}
+			// The libraries loaded before the error are closed.
+			for _, library := range append(alpha.libraries, beta.libraries...) {
+				if !library.closed {
+					t.Errorf("library %v isn't closed", library.names)
+				}
+			}
+		})
+	}
+}
+
+func TestReconfigureClosesLibraries(t *testing.T) {
+	alpha := &fakeBackend{name: "alpha"}
+	p := startFakeProvider(t, alpha)
+	requireNoDiagnostics(t, p.configure(map[string]cty.Value{"alpha": cty.StringVal("one")}))
+	first := alpha.libraries[0]
+	if first.closed {
+		t.Fatal("the library is closed while in use")
+	}
+
+	requireNoDiagnostics(t, p.configure(map[string]cty.Value{"alpha": cty.StringVal("two")}))
+	if !first.closed {
+		t.Error("the library of the previous configuration isn't closed")
+	}
+	if alpha.libraries[1].closed {
+		t.Error("the library of the current configuration is closed")
+	}
+	requireEqual(t, p.mustCall("two", cty.StringVal("a")), cty.StringVal("two a"))
+}
+
+func TestValidateBackends(t *testing.T) {
+	alpha := &fakeValidator{fakeBackend{name: "alpha"}}
+	p := startFakeProvider(t, alpha, &fakeBackend{name: "beta"})
+
+	// Only backends implementing Validator validate their source, and unknown sources are skipped.
+	requireNoDiagnostics(t, p.validate(map[string]cty.Value{"alpha": cty.UnknownVal(cty.String), "beta": cty.StringVal("invalid")}))
+
+	diags := p.validate(map[string]cty.Value{"alpha": cty.StringVal("invalid")})
+	if len(diags) != 2 {
+		t.Fatalf("got %d diagnostics, want 2: %v", len(diags), diags)
+	}
+	// The attribute of the diagnostics defaults to the source code attribute of the backend.
+	for i, want := range []*tftypes.AttributePath{
+		tftypes.NewAttributePath().WithAttributeName("alpha"),
+		tftypes.NewAttributePath().WithAttributeName("settings"),
+	} {
+		if !diags[i].Attribute.Equal(want) {
+			t.Errorf("diagnostic %d: got attribute %v, want %v", i, diags[i].Attribute, want)
+		}
+	}
+	if len(alpha.libraries) != 0 {
+		t.Error("validating loaded the source code")
+	}
+}
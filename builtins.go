@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"golang.org/x/mod/semver"
+)
+
+// builtinFunction is a function compiled into the provider, available without any configuration.
+type builtinFunction struct {
+	fn          any
+	params      []string
+	description string
+}
+
+var builtinFunctions = map[string]builtinFunction{
+	"semver_compare": {
+		fn:          semverCompare,
+		params:      []string{"a", "b"},
+		description: "Compares two semantic versions, returning -1 if a is lower than b, 0 if they're equal, and 1 if a is greater than b. The v prefix is optional.",
+	},
+	"cidr_contains": {
+		fn:          cidrContains,
+		params:      []string{"prefix", "address"},
+		description: "Reports whether the CIDR prefix contains the IP address, or all of the addresses of the given CIDR prefix.",
+	},
+	"regex_replace_all_named": {
+		fn:          regexReplaceAllNamed,
+		params:      []string{"input", "pattern", "replacement"},
+		description: "Replaces all matches of the regular expression in the input with the replacement, in which ${name} or $name is replaced by the text of the named capture group, and $$ by a $. Unknown group names and numbers are an error.",
+	},
+	"template_render": {
+		fn:          templateRender,
+		params:      []string{"template", "data"},
+		description: "Renders the Go text/template with the given data. Missing map keys are an error.",
+	},
+}
+
+// BuiltinFunctions returns the functions compiled into the provider.
+func BuiltinFunctions() map[string]*Function {
+	functions := make(map[string]*Function, len(builtinFunctions))
+	for name, builtin := range builtinFunctions {
+		fn, diags := GoFunctionToTFFunction(nil, reflect.ValueOf(builtin.fn), ConvertOptions{})
+		if len(diags) > 0 {
+			panic(fmt.Sprintf("builtin function %s: %s: %s", name, diags[0].Summary, diags[0].Detail))
+		}
+		for i, param := range builtin.params {
+			fn.Parameters[i].Name = param
+		}
+		fn.Description = builtin.description
+		functions[name] = fn
+	}
+	return functions
+}
+
+func semverCompare(a, b string) (int, error) {
+	a, b = semverCanonical(a), semverCanonical(b)
+	for _, version := range []string{a, b} {
+		if !semver.IsValid(version) {
+			return 0, fmt.Errorf("invalid semantic version %q", strings.TrimPrefix(version, "v"))
+		}
+	}
+	return semver.Compare(a, b), nil
+}
+
+// semverCanonical adds the v prefix expected by the semver package.
+func semverCanonical(version string) string {
+	if strings.HasPrefix(version, "v") {
+		return version
+	}
+	return "v" + version
+}
+
+func cidrContains(prefix netip.Prefix, address string) (bool, error) {
+	if strings.Contains(address, "/") {
+		other, err := netip.ParsePrefix(address)
+		if err != nil {
+			return false, err
+		}
+		return other.Bits() >= prefix.Bits() && prefix.Contains(other.Addr()), nil
+	}
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return false, err
+	}
+	return prefix.Contains(addr), nil
+}
+
+func regexReplaceAllNamed(input, pattern, replacement string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	for _, match := range replacementGroup.FindAllStringSubmatch(replacement, -1) {
+		name := match[1] + match[2]
+		if name == "" {
+			// $$ is an escaped $.
+			continue
+		}
+		if index, err := strconv.Atoi(name); err == nil {
+			if index > re.NumSubexp() {
+				return "", fmt.Errorf("the pattern has no capture group %d", index)
+			}
+			continue
+		}
+		if re.SubexpIndex(name) < 0 {
+			return "", fmt.Errorf("the pattern has no capture group named %s", name)
+		}
+	}
+	return re.ReplaceAllString(input, replacement), nil
+}
+
+// replacementGroup matches the escaped $ and the references to capture groups in regexp replacements, as ${name} or $name,
+// the latter taking the longest sequence of letters, digits and underscores like regexp.Expand does.
+var replacementGroup = regexp.MustCompile(`\$(?:\$|\{([\pL\p{Nd}_]+)\}|([\pL\p{Nd}_]+))`)
+
+func templateRender(text string, data any) (string, error) {
+	tmpl, err := template.New("template").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
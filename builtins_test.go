@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   string
+		args []cty.Value
+		want cty.Value
+	}{
+		{name: "semver lower", fn: "semver_compare", args: []cty.Value{cty.StringVal("1.2.0"), cty.StringVal("v1.10.0")}, want: cty.NumberIntVal(-1)},
+		{name: "semver equal", fn: "semver_compare", args: []cty.Value{cty.StringVal("v1.2.0"), cty.StringVal("1.2.0")}, want: cty.NumberIntVal(0)},
+		{name: "semver prerelease", fn: "semver_compare", args: []cty.Value{cty.StringVal("1.2.0"), cty.StringVal("1.2.0-rc.1")}, want: cty.NumberIntVal(1)},
+		{name: "cidr address", fn: "cidr_contains", args: []cty.Value{cty.StringVal("10.0.0.0/8"), cty.StringVal("10.1.2.3")}, want: cty.True},
+		{name: "cidr outside address", fn: "cidr_contains", args: []cty.Value{cty.StringVal("10.0.0.0/8"), cty.StringVal("192.168.0.1")}, want: cty.False},
+		{name: "cidr subnet", fn: "cidr_contains", args: []cty.Value{cty.StringVal("10.0.0.0/8"), cty.StringVal("10.1.0.0/16")}, want: cty.True},
+		{name: "cidr supernet", fn: "cidr_contains", args: []cty.Value{cty.StringVal("10.1.0.0/16"), cty.StringVal("10.0.0.0/8")}, want: cty.False},
+		{name: "cidr ipv6", fn: "cidr_contains", args: []cty.Value{cty.StringVal("2001:db8::/32"), cty.StringVal("2001:db8::1")}, want: cty.True},
+		{
+			name: "regex braces",
+			fn:   "regex_replace_all_named",
+			args: []cty.Value{cty.StringVal("2024-06"), cty.StringVal(`(?P<year>\d+)-(?P<month>\d+)`), cty.StringVal("${month}/${year}")},
+			want: cty.StringVal("06/2024"),
+		},
+		{
+			name: "regex bare name",
+			fn:   "regex_replace_all_named",
+			args: []cty.Value{cty.StringVal("2024-06"), cty.StringVal(`(?P<year>\d+)-(?P<month>\d+)`), cty.StringVal("$month $year")},
+			want: cty.StringVal("06 2024"),
+		},
+		{
+			name: "regex number",
+			fn:   "regex_replace_all_named",
+			args: []cty.Value{cty.StringVal("2024-06"), cty.StringVal(`(\d+)-(\d+)`), cty.StringVal("$2/${1}")},
+			want: cty.StringVal("06/2024"),
+		},
+		{
+			name: "regex escaped dollar",
+			fn:   "regex_replace_all_named",
+			args: []cty.Value{cty.StringVal("price: 5"), cty.StringVal(`(?P<amount>\d+)`), cty.StringVal("$$price $$$amount")},
+			want: cty.StringVal("price: $price $5"),
+		},
+		{
+			name: "template",
+			fn:   "template_render",
+			args: []cty.Value{cty.StringVal("Hello, {{.name}}!"), cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("papaya")})},
+			want: cty.StringVal("Hello, papaya!"),
+		},
+		{
+			name: "template range",
+			fn:   "template_render",
+			args: []cty.Value{cty.StringVal("{{range .}}{{.}};{{end}}"), cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")})},
+			want: cty.StringVal("a;b;"),
+		},
+	}
+	p := startProvider(t, "")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requireEqual(t, p.mustCall(test.fn, test.args...), test.want)
+		})
+	}
+}
+
+func TestBuiltinFunctionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   string
+		args []cty.Value
+		text string
+	}{
+		{name: "invalid semver", fn: "semver_compare", args: []cty.Value{cty.StringVal("1.2"), cty.StringVal("one")}, text: `invalid semantic version "one"`},
+		{name: "invalid prefix", fn: "cidr_contains", args: []cty.Value{cty.StringVal("10.0.0.0"), cty.StringVal("10.1.2.3")}, text: "no '/'"},
+		{name: "invalid address", fn: "cidr_contains", args: []cty.Value{cty.StringVal("10.0.0.0/8"), cty.StringVal("10.1.2")}, text: "IPv4 address too short"},
+		{name: "invalid subnet", fn: "cidr_contains", args: []cty.Value{cty.StringVal("10.0.0.0/8"), cty.StringVal("10.1.0.0/99")}, text: "prefix length out of range"},
+		{
+			name: "invalid pattern",
+			fn:   "regex_replace_all_named",
+			args: []cty.Value{cty.StringVal("2024"), cty.StringVal(`(\d+`), cty.StringVal("$1")},
+			text: "missing closing )",
+		},
+		{
+			name: "unknown braced name",
+			fn:   "regex_replace_all_named",
+			args: []cty.Value{cty.StringVal("2024-06"), cty.StringVal(`(?P<year>\d+)-(?P<month>\d+)`), cty.StringVal("${day}/${month}")},
+			text: "no capture group named day",
+		},
+		{
+			name: "unknown bare name",
+			fn:   "regex_replace_all_named",
+			args: []cty.Value{cty.StringVal("2024-06"), cty.StringVal(`(?P<year>\d+)-(?P<month>\d+)`), cty.StringVal("$month_$year")},
+			text: "no capture group named month_",
+		},
+		{
+			name: "unknown number",
+			fn:   "regex_replace_all_named",
+			args: []cty.Value{cty.StringVal("2024-06"), cty.StringVal(`(\d+)-(\d+)`), cty.StringVal("$3")},
+			text: "no capture group 3",
+		},
+		{
+			name: "invalid template",
+			fn:   "template_render",
+			args: []cty.Value{cty.StringVal("Hello, {{.name}!"), cty.EmptyObjectVal},
+			text: "bad character",
+		},
+		{
+			name: "missing key",
+			fn:   "template_render",
+			args: []cty.Value{cty.StringVal("Hello, {{.name}}!"), cty.MapVal(map[string]cty.Value{"title": cty.StringVal("papaya")})},
+			text: `map has no entry for key "name"`,
+		},
+	}
+	p := startProvider(t, "")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, funcErr := p.call(test.fn, test.args...)
+			if funcErr == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(funcErr.Text, test.text) {
+				t.Errorf("got error %q, want it to contain %q", funcErr.Text, test.text)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// commands are the subcommands of the binary, which let the code be used without Tofu.
+// Without any, the binary serves the provider to Tofu.
+var commands = map[string]func(args []string) error{
+	"call":   callCommand,
+	"docs":   docsCommand,
+	"repl":   replCommand,
+	"schema": schemaCommand,
+	"test":   testCommand,
+}
+
+func runCommand(args []string) error {
+	command, ok := commands[args[0]]
+	if !ok {
+		var names []string
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown command %q, expected one of %s", args[0], strings.Join(names, ", "))
+	}
+	return command(args[1:])
+}
+
+// callCommand calls a function of a source file with JSON arguments, and prints its JSON result, e.g.:
+//
+//	terraform-provider-go call lib.go hello '"papaya"'
+func callCommand(args []string) error {
+	flags := flag.NewFlagSet("call", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: terraform-provider-go call [-settings JSON] FILE FUNCTION [JSON ARGUMENTS...]")
+		flags.PrintDefaults()
+	}
+	settings := flags.String("settings", "", "the settings of the provider, as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return errors.New("missing file or function")
+	}
+
+	provider, err := configureFile(flags.Arg(0), *settings)
+	if err != nil {
+		return err
+	}
+	result, err := callJSON(provider, flags.Arg(1), flags.Args()[2:])
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(result))
+	return nil
+}
+
+// sourceBackends are the backends of source files, by extension.
+var sourceBackends = map[string]string{
+	".go":   "go",
+	".lua":  "lua",
+	".star": "starlark",
+	".wasm": "wasm",
+}
+
+// configureFile returns the provider configured with the source file, as Tofu would configure it,
+// along with the static library given by the environment. Warnings are printed to stderr.
+func configureFile(path, settings string) (*FunctionProvider, error) {
+	library, err := loadStaticLibrary()
+	if err != nil {
+		return nil, err
+	}
+	provider, err := newProvider(library)
+	if err != nil {
+		return nil, err
+	}
+
+	backend, ok := sourceBackends[filepath.Ext(path)]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown source file extension, expected .go, .lua, .star or .wasm", path)
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if backend == "wasm" {
+		source = []byte(base64.StdEncoding.EncodeToString(source))
+	}
+
+	ctyType, err := TFTypeToCtyType(provider.ProviderSchema.ValueType())
+	if err != nil {
+		return nil, err
+	}
+	attributes := map[string]cty.Value{}
+	for name, attributeType := range ctyType.AttributeTypes() {
+		attributes[name] = cty.NullVal(attributeType)
+	}
+	attributes[backend] = cty.StringVal(string(source))
+	if settings != "" {
+		attributes["settings"], err = jsonToCty(cty.DynamicPseudoType, []byte(settings))
+		if err != nil {
+			return nil, fmt.Errorf("settings: %w", formatCtyError(err))
+		}
+	}
+	config, err := CtyToProto(ctyType, cty.ObjectVal(attributes))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	validateResp, err := provider.ValidateProviderConfig(ctx, &tfprotov6.ValidateProviderConfigRequest{Config: config})
+	if err != nil {
+		return nil, err
+	}
+	if err := printDiagnostics(os.Stderr, path, validateResp.Diagnostics); err != nil {
+		return nil, err
+	}
+	configureResp, err := provider.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{Config: config})
+	if err != nil {
+		return nil, err
+	}
+	if err := printDiagnostics(os.Stderr, path, configureResp.Diagnostics); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// callJSON calls the function of the provider with JSON arguments, and returns its JSON result.
+// The arguments of dynamic parameters have the type implied by their JSON value.
+func callJSON(provider *FunctionProvider, name string, args []string) ([]byte, error) {
+	fn, err := providerFunction(provider, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkArguments(name, fn, len(args)); err != nil {
+		return nil, err
+	}
+	var values []cty.Value
+	for i, arg := range args {
+		param := fn.VariadicParameter
+		if i < len(fn.Parameters) {
+			param = fn.Parameters[i]
+		}
+		ctyType, err := TFTypeToCtyType(param.Type)
+		if err != nil {
+			return nil, err
+		}
+		value, err := jsonToCty(ctyType, []byte(arg))
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i+1, formatCtyError(err))
+		}
+		values = append(values, value)
+	}
+
+	result, err := callFunction(provider, name, fn, values)
+	if err != nil {
+		return nil, err
+	}
+	return ctyjson.Marshal(result, result.Type())
+}
+
+// providerFunction returns the signature of the function of the provider.
+func providerFunction(provider *FunctionProvider, name string) (*tfprotov6.Function, error) {
+	functions, err := provider.GetFunctions(context.Background(), &tfprotov6.GetFunctionsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := functions.Functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	return fn, nil
+}
+
+// checkArguments checks the number of arguments passed to the function.
+func checkArguments(name string, fn *tfprotov6.Function, n int) error {
+	if n < len(fn.Parameters) || (n > len(fn.Parameters) && fn.VariadicParameter == nil) {
+		return fmt.Errorf("function %s takes %d arguments, got %d", name, len(fn.Parameters), n)
+	}
+	return nil
+}
+
+// callFunction calls the function of the provider through the protocol, like Tofu does.
+func callFunction(provider *FunctionProvider, name string, fn *tfprotov6.Function, args []cty.Value) (cty.Value, error) {
+	if err := checkArguments(name, fn, len(args)); err != nil {
+		return cty.NilVal, err
+	}
+	var arguments []*tfprotov6.DynamicValue
+	for i, arg := range args {
+		param := fn.VariadicParameter
+		if i < len(fn.Parameters) {
+			param = fn.Parameters[i]
+		}
+		ctyType, err := TFTypeToCtyType(param.Type)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		argument, err := CtyToProto(ctyType, arg)
+		if err != nil {
+			return cty.NilVal, fmt.Errorf("argument %d: %w", i+1, formatCtyError(err))
+		}
+		arguments = append(arguments, argument)
+	}
+
+	resp, err := provider.CallFunction(context.Background(), &tfprotov6.CallFunctionRequest{Name: name, Arguments: arguments})
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if resp.Error != nil {
+		if resp.Error.FunctionArgument != nil {
+			return cty.NilVal, fmt.Errorf("argument %d: %s", *resp.Error.FunctionArgument+1, resp.Error.Text)
+		}
+		return cty.NilVal, errors.New(resp.Error.Text)
+	}
+	returnType, err := TFTypeToCtyType(fn.Return.Type)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	result, err := ProtoToCty(returnType, resp.Result)
+	if err != nil {
+		return cty.NilVal, formatCtyError(err)
+	}
+	return result, nil
+}
+
+// jsonToCty decodes a JSON value of the given type, which is implied by the value if it's dynamic.
+func jsonToCty(ctyType cty.Type, data []byte) (cty.Value, error) {
+	if ctyType == cty.DynamicPseudoType {
+		var err error
+		ctyType, err = ctyjson.ImpliedType(data)
+		if err != nil {
+			return cty.NilVal, err
+		}
+	}
+	return ctyjson.Unmarshal(data, ctyType)
+}
+
+// printDiagnostics prints the diagnostics about the file, and returns an error if any of them is an error.
+func printDiagnostics(w io.Writer, path string, diags []*tfprotov6.Diagnostic) error {
+	var errs int
+	for _, diag := range diags {
+		severity := "Warning"
+		if diag.Severity == tfprotov6.DiagnosticSeverityError {
+			severity = "Error"
+			errs++
+		}
+		fmt.Fprintf(w, "%s: %s: %s\n", severity, path, diag.Summary)
+		if diag.Detail != "" {
+			fmt.Fprintf(w, "\n%s\n\n", diag.Detail)
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("failed to load %s", path)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSource writes the source code to a file with the given name in a temporary directory, and returns its path.
+func writeSource(t *testing.T, name, source string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCallJSON(t *testing.T) {
+	t.Setenv(libraryEnv, "")
+	tests := []struct {
+		name     string
+		file     string
+		source   string
+		settings string
+		fn       string
+		args     []string
+		want     string
+	}{
+		{
+			name:   "go",
+			file:   "lib.go",
+			source: "package lib\nfunc Hello(name string) string { return \"Hello, \" + name + \"!\" }",
+			fn:     "hello",
+			args:   []string{`"papaya"`},
+			want:   `"Hello, papaya!"`,
+		},
+		{
+			name:     "go settings",
+			file:     "lib.go",
+			source:   "package lib\nvar env string\nfunc Init(settings struct{ Env string }) error { env = settings.Env; return nil }\nfunc Env() string { return env }",
+			settings: `{"env": "prod"}`,
+			fn:       "env",
+			want:     `"prod"`,
+		},
+		{
+			name:   "lua",
+			file:   "lib.lua",
+			source: "function add(a, b) return a + b end\nreturn { add = { params = { \"number\", \"number\" }, returns = \"number\" } }",
+			fn:     "add",
+			args:   []string{"1", "2"},
+			want:   "3",
+		},
+		{
+			name:   "starlark",
+			file:   "lib.star",
+			source: "def upper(s):\n    return s.upper()\n\nsignature(upper, params = [\"string\"], returns = \"string\")\n",
+			fn:     "upper",
+			args:   []string{`"papaya"`},
+			want:   `"PAPAYA"`,
+		},
+		{
+			name:   "dynamic argument",
+			file:   "lib.go",
+			source: "package lib\nimport \"fmt\"\nfunc Describe(v any) string { return fmt.Sprint(v) }",
+			fn:     "describe",
+			args:   []string{`{"a": [true]}`},
+			want:   `"map[a:[true]]"`,
+		},
+		{
+			name:   "builtin",
+			file:   "lib.go",
+			source: "package lib",
+			fn:     "semver_compare",
+			args:   []string{`"1.2.0"`, `"1.10.0"`},
+			want:   "-1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider, err := configureFile(writeSource(t, test.file, test.source), test.settings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := callJSON(provider, test.fn, test.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCallJSONWasm(t *testing.T) {
+	t.Setenv(libraryEnv, "")
+	provider, err := configureFile(filepath.Join("testdata", "wasm", "lib.wasm"), `{"env": "prod"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := callJSON(provider, "settings", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"env":"prod"}`; string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestConfigureFileErrors(t *testing.T) {
+	t.Setenv(libraryEnv, "")
+	tests := []struct {
+		name     string
+		path     string
+		settings string
+		err      string
+	}{
+		{name: "unknown extension", path: writeSource(t, "lib.py", "def hello(): pass"), err: "unknown source file extension"},
+		{name: "missing file", path: filepath.Join(t.TempDir(), "lib.go"), err: "no such file"},
+		{name: "invalid code", path: writeSource(t, "lib.go", "package lib\nfunc Hello( {"), err: "failed to load"},
+		{name: "invalid settings", path: writeSource(t, "lib.go", "package lib"), settings: "{", err: "settings:"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := configureFile(test.path, test.settings)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("got error %v, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestCallJSONErrors(t *testing.T) {
+	t.Setenv(libraryEnv, "")
+	provider, err := configureFile(writeSource(t, "lib.go", `package lib
+
+import "errors"
+
+func Hello(name string) string { return "Hello, " + name + "!" }
+
+func Fail(s string) (string, error) { return "", errors.New("failed on " + s) }
+`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		fn   string
+		args []string
+		err  string
+	}{
+		{name: "unknown function", fn: "greet", args: []string{`"papaya"`}, err: "unknown function greet"},
+		{name: "missing argument", fn: "hello", err: "function hello takes 1 arguments, got 0"},
+		{name: "extra argument", fn: "hello", args: []string{`"a"`, `"b"`}, err: "function hello takes 1 arguments, got 2"},
+		{name: "invalid JSON", fn: "hello", args: []string{"papaya"}, err: "argument 1:"},
+		{name: "wrong type", fn: "hello", args: []string{`["papaya"]`}, err: "argument 1:"},
+		{name: "function error", fn: "fail", args: []string{`"papaya"`}, err: "failed on papaya"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := callJSON(provider, test.fn, test.args)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("got error %v, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
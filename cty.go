@@ -0,0 +1,750 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/gocty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// Values are converted between the protocol and Go through cty, which is also the value model used by OpenTofu itself.
+// This gives us the same semantics as OpenTofu for sets, unknown values and number precision.
+//
+//	*tfprotov6.DynamicValue <-> cty.Value <-> Go
+
+// TFTypeToCtyType converts a protocol type to the equivalent cty type.
+func TFTypeToCtyType(t tftypes.Type) (cty.Type, error) {
+	switch {
+	case t == nil:
+		return cty.NilType, errors.New("missing type")
+	case t.Is(tftypes.String):
+		return cty.String, nil
+	case t.Is(tftypes.Number):
+		return cty.Number, nil
+	case t.Is(tftypes.Bool):
+		return cty.Bool, nil
+	case t.Is(tftypes.DynamicPseudoType):
+		return cty.DynamicPseudoType, nil
+	}
+
+	switch t := t.(type) {
+	case tftypes.List:
+		elementType, err := TFTypeToCtyType(t.ElementType)
+		if err != nil {
+			return cty.NilType, err
+		}
+		return cty.List(elementType), nil
+	case tftypes.Set:
+		elementType, err := TFTypeToCtyType(t.ElementType)
+		if err != nil {
+			return cty.NilType, err
+		}
+		return cty.Set(elementType), nil
+	case tftypes.Map:
+		elementType, err := TFTypeToCtyType(t.ElementType)
+		if err != nil {
+			return cty.NilType, err
+		}
+		return cty.Map(elementType), nil
+	case tftypes.Tuple:
+		elementTypes := make([]cty.Type, len(t.ElementTypes))
+		for i := range t.ElementTypes {
+			elementType, err := TFTypeToCtyType(t.ElementTypes[i])
+			if err != nil {
+				return cty.NilType, err
+			}
+			elementTypes[i] = elementType
+		}
+		return cty.Tuple(elementTypes), nil
+	case tftypes.Object:
+		attributeTypes := make(map[string]cty.Type, len(t.AttributeTypes))
+		for name, attributeType := range t.AttributeTypes {
+			ctyType, err := TFTypeToCtyType(attributeType)
+			if err != nil {
+				return cty.NilType, err
+			}
+			attributeTypes[name] = ctyType
+		}
+		var optional []string
+		for name := range t.OptionalAttributes {
+			optional = append(optional, name)
+		}
+		return cty.ObjectWithOptionalAttrs(attributeTypes, optional), nil
+	default:
+		return cty.NilType, fmt.Errorf("unsupported type %s", t.String())
+	}
+}
+
+// CtyTypeToTFType converts a cty type to the equivalent protocol type.
+func CtyTypeToTFType(t cty.Type) (tftypes.Type, error) {
+	switch {
+	case t == cty.String:
+		return tftypes.String, nil
+	case t == cty.Number:
+		return tftypes.Number, nil
+	case t == cty.Bool:
+		return tftypes.Bool, nil
+	case t == cty.DynamicPseudoType:
+		return tftypes.DynamicPseudoType, nil
+	case t.IsListType():
+		elementType, err := CtyTypeToTFType(t.ElementType())
+		if err != nil {
+			return nil, err
+		}
+		return tftypes.List{ElementType: elementType}, nil
+	case t.IsSetType():
+		elementType, err := CtyTypeToTFType(t.ElementType())
+		if err != nil {
+			return nil, err
+		}
+		return tftypes.Set{ElementType: elementType}, nil
+	case t.IsMapType():
+		elementType, err := CtyTypeToTFType(t.ElementType())
+		if err != nil {
+			return nil, err
+		}
+		return tftypes.Map{ElementType: elementType}, nil
+	case t.IsTupleType():
+		elementTypes := make([]tftypes.Type, len(t.TupleElementTypes()))
+		for i, ctyType := range t.TupleElementTypes() {
+			elementType, err := CtyTypeToTFType(ctyType)
+			if err != nil {
+				return nil, err
+			}
+			elementTypes[i] = elementType
+		}
+		return tftypes.Tuple{ElementTypes: elementTypes}, nil
+	case t.IsObjectType():
+		attributeTypes := make(map[string]tftypes.Type, len(t.AttributeTypes()))
+		optional := make(map[string]struct{})
+		for name, ctyType := range t.AttributeTypes() {
+			attributeType, err := CtyTypeToTFType(ctyType)
+			if err != nil {
+				return nil, err
+			}
+			attributeTypes[name] = attributeType
+			if t.AttributeOptional(name) {
+				optional[name] = struct{}{}
+			}
+		}
+		if len(optional) == 0 {
+			optional = nil
+		}
+		return tftypes.Object{AttributeTypes: attributeTypes, OptionalAttributes: optional}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t.FriendlyName())
+	}
+}
+
+// ParseTypeExpr parses a Tofu type constraint, like "string", "any" or "list(object({ name = string }))".
+// It's used to declare the function signatures of the languages without static types.
+func ParseTypeExpr(src string) (tftypes.Type, error) {
+	expr, diags := hclsyntax.ParseExpression([]byte(src), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid type %q: %s", src, diags[0].Detail)
+	}
+	ctyType, diags := typeexpr.TypeConstraint(expr)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid type %q: %s", src, diags[0].Detail)
+	}
+	return CtyTypeToTFType(ctyType)
+}
+
+// ProtoToCty decodes a protocol value of the given type.
+func ProtoToCty(ctyType cty.Type, value *tfprotov6.DynamicValue) (cty.Value, error) {
+	switch {
+	case value == nil:
+		return cty.NullVal(ctyType), nil
+	case len(value.MsgPack) > 0:
+		return ctymsgpack.Unmarshal(value.MsgPack, ctyType)
+	case len(value.JSON) > 0:
+		return ctyjson.Unmarshal(value.JSON, ctyType)
+	default:
+		// This happens when you pass (from tf) the value `null`, to a function expecting e.g. a string pointer.
+		return cty.NullVal(ctyType), nil
+	}
+}
+
+// CtyToProto encodes a value as a protocol value of the given type.
+// The type may contain cty.DynamicPseudoType, in which case the concrete type is encoded alongside the value.
+func CtyToProto(ctyType cty.Type, value cty.Value) (*tfprotov6.DynamicValue, error) {
+	msgpack, err := ctymsgpack.Marshal(value, ctyType)
+	if err != nil {
+		return nil, err
+	}
+	return &tfprotov6.DynamicValue{MsgPack: msgpack}, nil
+}
+
+// CtyToGo converts a cty value to a Go value of the given type.
+// Null values become the zero value of the type, which should be a pointer for nullable values.
+func CtyToGo(goType reflect.Type, ctyValue cty.Value, opts ConvertOptions) (any, error) {
+	ctyValue, _ = ctyValue.UnmarkDeep()
+	out, err := ctyToGoValue(goType, ctyValue, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	return out.Interface(), nil
+}
+
+func ctyToGoValue(goType reflect.Type, ctyValue cty.Value, opts ConvertOptions, path cty.Path) (reflect.Value, error) {
+	if !ctyValue.IsKnown() {
+		return reflect.Value{}, path.NewErrorf("value must be known")
+	}
+	if ctyValue.IsNull() {
+		return reflect.Zero(goType), nil
+	}
+	ctyType := ctyValue.Type()
+
+	if conv, ok := opts.Types[goType]; ok {
+		generic, err := ctyToAny(ctyValue, path)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		value, err := conv.FromTF(generic)
+		if err != nil {
+			return reflect.Value{}, path.NewError(err)
+		}
+		if reflect.TypeOf(value) != goType {
+			return reflect.Value{}, path.NewErrorf("conversion of %s returned %T", goType, value)
+		}
+		return reflect.ValueOf(value), nil
+	}
+
+	if isBytes(goType) || goType == timeType || goType == durationType || isText(goType) {
+		if goType == durationType && opts.DurationFormat == "seconds" {
+			if ctyType != cty.Number {
+				return reflect.Value{}, path.NewErrorf("number required")
+			}
+			d, err := secondsToDuration(ctyValue.AsBigFloat())
+			if err != nil {
+				return reflect.Value{}, path.NewError(err)
+			}
+			return reflect.ValueOf(d), nil
+		}
+
+		if ctyType != cty.String {
+			return reflect.Value{}, path.NewErrorf("string required")
+		}
+		str := ctyValue.AsString()
+		switch {
+		case isBytes(goType):
+			b, err := opts.decodeBytes(str)
+			if err != nil {
+				return reflect.Value{}, path.NewError(err)
+			}
+			return reflect.ValueOf(b).Convert(goType), nil
+		case goType == timeType:
+			t, err := time.Parse(time.RFC3339, str)
+			if err != nil {
+				return reflect.Value{}, path.NewErrorf("invalid RFC 3339 timestamp %q", str)
+			}
+			return reflect.ValueOf(t), nil
+		case goType == durationType:
+			d, err := time.ParseDuration(str)
+			if err != nil {
+				return reflect.Value{}, path.NewErrorf("invalid duration %q", str)
+			}
+			return reflect.ValueOf(d), nil
+		default:
+			value, err := unmarshalText(goType, str)
+			if err != nil {
+				return reflect.Value{}, path.NewError(err)
+			}
+			return reflect.ValueOf(value), nil
+		}
+	}
+
+	switch goType.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		out := reflect.New(goType)
+		if err := gocty.FromCtyValue(ctyValue, out.Interface()); err != nil {
+			return reflect.Value{}, path.NewError(err)
+		}
+		return out.Elem(), nil
+	case reflect.Ptr:
+		value, err := ctyToGoValue(goType.Elem(), ctyValue, opts, path)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		// The pointed-to value has to be addressable, so we allocate it and copy the value in.
+		out := reflect.New(goType.Elem())
+		out.Elem().Set(value)
+		return out, nil
+	case reflect.Interface:
+		value, err := ctyToAny(ctyValue, path)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if value == nil {
+			return reflect.Zero(goType), nil
+		}
+		return reflect.ValueOf(value), nil
+	case reflect.Slice:
+		if !ctyType.IsListType() && !ctyType.IsSetType() && !ctyType.IsTupleType() {
+			return reflect.Value{}, path.NewErrorf("list required")
+		}
+		out := reflect.MakeSlice(goType, ctyValue.LengthInt(), ctyValue.LengthInt())
+		// Set elements are keyed by themselves, so we count the elements instead of using the keys.
+		for i, it := 0, ctyValue.ElementIterator(); it.Next(); i++ {
+			key, ctyElement := it.Element()
+			elem, err := ctyToGoValue(goType.Elem(), ctyElement, opts, path.Index(key))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Index(i).Set(elem)
+		}
+		return out, nil
+	case reflect.Map:
+		if goType.Key().Kind() != reflect.String {
+			return reflect.Value{}, path.NewErrorf("unsupported map key type %s, only string keys are supported", goType.Key().String())
+		}
+		if !ctyType.IsMapType() && !ctyType.IsObjectType() {
+			return reflect.Value{}, path.NewErrorf("map required")
+		}
+		out := reflect.MakeMapWithSize(goType, ctyValue.LengthInt())
+		for key, ctyElement := range ctyValue.AsValueMap() {
+			elem, err := ctyToGoValue(goType.Elem(), ctyElement, opts, path.Index(cty.StringVal(key)))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.SetMapIndex(reflect.ValueOf(key).Convert(goType.Key()), elem)
+		}
+		return out, nil
+	case reflect.Struct:
+		if !ctyType.IsObjectType() {
+			return reflect.Value{}, path.NewErrorf("object required")
+		}
+		// This is a fun one, you'd fine reflect.Zero(goType) should do the same, right?
+		// Nope! If you use reflect.Zero, then the fields of it won't be addressable.
+		// If the fields aren't addressable, they're not settable.
+		// So, we use reflect.New and then take the pointed-to value, this way it is in fact addressable.
+		out := reflect.New(goType).Elem()
+		for i := 0; i < goType.NumField(); i++ {
+			field := goType.Field(i)
+			tfName := getTfObjectGoFieldName(field)
+			if !ctyType.HasAttribute(tfName) {
+				return reflect.Value{}, path.NewErrorf("missing object field %s", tfName)
+			}
+			fieldOpts, err := opts.withField(field)
+			if err != nil {
+				return reflect.Value{}, path.NewError(err)
+			}
+			elem, err := ctyToGoValue(field.Type, ctyValue.GetAttr(tfName), fieldOpts, path.GetAttr(tfName))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Field(i).Set(elem)
+		}
+		return out, nil
+	default:
+		return reflect.Value{}, path.NewErrorf("unsupported type %s", goType.String())
+	}
+}
+
+// ctyToAny converts a cty value to its generic Go representation,
+// i.e. string, bool, float64, []any or map[string]any.
+func ctyToAny(ctyValue cty.Value, path cty.Path) (any, error) {
+	if !ctyValue.IsKnown() {
+		return nil, path.NewErrorf("value must be known")
+	}
+	if ctyValue.IsNull() {
+		return nil, nil
+	}
+
+	ctyType := ctyValue.Type()
+	switch {
+	case ctyType == cty.String:
+		return ctyValue.AsString(), nil
+	case ctyType == cty.Bool:
+		return ctyValue.True(), nil
+	case ctyType == cty.Number:
+		f, _ := ctyValue.AsBigFloat().Float64()
+		return f, nil
+	case ctyType.IsListType(), ctyType.IsSetType(), ctyType.IsTupleType():
+		out := make([]any, 0, ctyValue.LengthInt())
+		for it := ctyValue.ElementIterator(); it.Next(); {
+			key, ctyElement := it.Element()
+			elem, err := ctyToAny(ctyElement, path.Index(key))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, elem)
+		}
+		return out, nil
+	case ctyType.IsMapType(), ctyType.IsObjectType():
+		out := make(map[string]any, ctyValue.LengthInt())
+		for key, ctyElement := range ctyValue.AsValueMap() {
+			elem, err := ctyToAny(ctyElement, path.Index(cty.StringVal(key)))
+			if err != nil {
+				return nil, err
+			}
+			out[key] = elem
+		}
+		return out, nil
+	default:
+		return nil, path.NewErrorf("unsupported type %s", ctyType.FriendlyName())
+	}
+}
+
+// GoToCty converts a Go value to a cty value of the given type.
+// If the type is, or contains, cty.DynamicPseudoType, the concrete type is inferred from the Go value.
+func GoToCty(ctyType cty.Type, value any, opts ConvertOptions) (cty.Value, error) {
+	return goToCtyValue(ctyType, reflect.ValueOf(value), opts, nil)
+}
+
+func goToCtyValue(ctyType cty.Type, value reflect.Value, opts ConvertOptions, path cty.Path) (cty.Value, error) {
+	if !value.IsValid() {
+		return cty.NullVal(ctyType), nil
+	}
+	if conv, ok := opts.Types[value.Type()]; ok {
+		generic, err := conv.ToTF(value.Interface())
+		if err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+		if reflect.TypeOf(generic) == value.Type() {
+			return cty.NilVal, path.NewErrorf("conversion of %s must return a generic value", value.Type())
+		}
+		convType, err := TFTypeToCtyType(conv.Type)
+		if err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+		return goToCtyValue(convType, reflect.ValueOf(generic), opts, path)
+	}
+	if value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		// Pointers are nullable, otherwise they're represented the same as the value they point to.
+		if value.IsNil() {
+			return cty.NullVal(ctyType), nil
+		}
+		return goToCtyValue(ctyType, value.Elem(), opts, path)
+	}
+	if ctyType == cty.DynamicPseudoType {
+		concreteType, err := inferCtyType(value, opts, path)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		return goToCtyValue(concreteType, value, opts, path)
+	}
+
+	goType := value.Type()
+	switch {
+	case ctyType == cty.String:
+		switch {
+		case isBytes(goType):
+			str, err := opts.encodeBytes(value.Bytes())
+			if err != nil {
+				return cty.NilVal, path.NewError(err)
+			}
+			return cty.StringVal(str), nil
+		case goType == timeType:
+			// Unlike Format, MarshalText rejects the years that can't be parsed back, outside of [0, 9999].
+			text, err := value.Interface().(time.Time).MarshalText()
+			if err != nil {
+				return cty.NilVal, path.NewError(err)
+			}
+			return cty.StringVal(string(text)), nil
+		case goType == durationType:
+			return cty.StringVal(value.Interface().(time.Duration).String()), nil
+		case isText(goType):
+			str, err := marshalText(value)
+			if err != nil {
+				return cty.NilVal, path.NewError(err)
+			}
+			return cty.StringVal(str), nil
+		case goType.Kind() == reflect.String:
+			return cty.StringVal(value.String()), nil
+		}
+		return cty.NilVal, path.NewErrorf("expected string, got %s", goType)
+	case ctyType == cty.Bool:
+		if goType.Kind() != reflect.Bool {
+			return cty.NilVal, path.NewErrorf("expected bool, got %s", goType)
+		}
+		return cty.BoolVal(value.Bool()), nil
+	case ctyType == cty.Number:
+		switch goType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if goType == durationType {
+				return cty.NumberVal(durationToSeconds(value.Interface().(time.Duration))), nil
+			}
+			return cty.NumberIntVal(value.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return cty.NumberUIntVal(value.Uint()), nil
+		case reflect.Float32, reflect.Float64:
+			if math.IsNaN(value.Float()) {
+				return cty.NilVal, path.NewErrorf("NaN can't be represented as a number")
+			}
+			return cty.NumberFloatVal(value.Float()), nil
+		}
+		return cty.NilVal, path.NewErrorf("expected number, got %s", goType)
+	case ctyType.IsListType(), ctyType.IsSetType():
+		if goType.Kind() != reflect.Slice && goType.Kind() != reflect.Array {
+			return cty.NilVal, path.NewErrorf("expected slice, got %s", goType)
+		}
+		elems := make([]cty.Value, value.Len())
+		for i := range elems {
+			elem, err := goToCtyValue(ctyType.ElementType(), value.Index(i), opts, path.Index(cty.NumberIntVal(int64(i))))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			elems[i] = elem
+		}
+		elems, elementType, err := unifyElements(ctyType.ElementType(), elems, path)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		switch {
+		case ctyType.IsListType() && len(elems) == 0:
+			return cty.ListValEmpty(elementType), nil
+		case ctyType.IsListType():
+			return cty.ListVal(elems), nil
+		case len(elems) == 0:
+			return cty.SetValEmpty(elementType), nil
+		default:
+			return cty.SetVal(elems), nil
+		}
+	case ctyType.IsTupleType():
+		if goType.Kind() != reflect.Slice && goType.Kind() != reflect.Array {
+			return cty.NilVal, path.NewErrorf("expected slice, got %s", goType)
+		}
+		elementTypes := ctyType.TupleElementTypes()
+		if value.Len() != len(elementTypes) {
+			return cty.NilVal, path.NewErrorf("expected %d elements, got %d", len(elementTypes), value.Len())
+		}
+		elems := make([]cty.Value, value.Len())
+		for i := range elems {
+			elem, err := goToCtyValue(elementTypes[i], value.Index(i), opts, path.Index(cty.NumberIntVal(int64(i))))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			elems[i] = elem
+		}
+		return cty.TupleVal(elems), nil
+	case ctyType.IsMapType():
+		if goType.Kind() != reflect.Map || goType.Key().Kind() != reflect.String {
+			return cty.NilVal, path.NewErrorf("expected map, got %s", goType)
+		}
+		keys := make([]string, 0, value.Len())
+		elems := make([]cty.Value, 0, value.Len())
+		for it := value.MapRange(); it.Next(); {
+			key := it.Key().String()
+			elem, err := goToCtyValue(ctyType.ElementType(), it.Value(), opts, path.Index(cty.StringVal(key)))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			keys = append(keys, key)
+			elems = append(elems, elem)
+		}
+		elems, elementType, err := unifyElements(ctyType.ElementType(), elems, path)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		if len(elems) == 0 {
+			return cty.MapValEmpty(elementType), nil
+		}
+		out := make(map[string]cty.Value, len(elems))
+		for i := range keys {
+			// Tofu normalizes strings to Unicode NFC, which can make distinct Go keys equal.
+			key := cty.StringVal(keys[i]).AsString()
+			if _, ok := out[key]; ok {
+				return cty.NilVal, path.NewErrorf("duplicate map key %q once normalized to Unicode NFC", key)
+			}
+			out[key] = elems[i]
+		}
+		return cty.MapVal(out), nil
+	case ctyType.IsObjectType():
+		attrs := make(map[string]reflect.Value, len(ctyType.AttributeTypes()))
+		attrOpts := make(map[string]ConvertOptions, len(ctyType.AttributeTypes()))
+		switch {
+		case goType.Kind() == reflect.Map && goType.Key().Kind() == reflect.String:
+			for it := value.MapRange(); it.Next(); {
+				attrs[it.Key().String()] = it.Value()
+				attrOpts[it.Key().String()] = opts
+			}
+		case goType.Kind() == reflect.Struct:
+			for i := 0; i < goType.NumField(); i++ {
+				field := goType.Field(i)
+				fieldOpts, err := opts.withField(field)
+				if err != nil {
+					return cty.NilVal, path.NewError(err)
+				}
+				attrs[getTfObjectGoFieldName(field)] = value.Field(i)
+				attrOpts[getTfObjectGoFieldName(field)] = fieldOpts
+			}
+		default:
+			return cty.NilVal, path.NewErrorf("expected struct, got %s", goType)
+		}
+		out := make(map[string]cty.Value, len(ctyType.AttributeTypes()))
+		for name, attributeType := range ctyType.AttributeTypes() {
+			// Missing map keys simply become null attributes.
+			attr, err := goToCtyValue(attributeType, attrs[name], attrOpts[name], path.GetAttr(name))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			out[name] = attr
+		}
+		return cty.ObjectVal(out), nil
+	default:
+		return cty.NilVal, path.NewErrorf("unsupported type %s", ctyType.FriendlyName())
+	}
+}
+
+// durationToSeconds returns the number of seconds of a duration.
+// Unlike Duration.Seconds, it's precise enough for secondsToDuration to give back the same duration.
+func durationToSeconds(d time.Duration) *big.Float {
+	nanoseconds := new(big.Float).SetPrec(512).SetInt64(int64(d))
+	return nanoseconds.Quo(nanoseconds, big.NewFloat(float64(time.Second)))
+}
+
+// secondsToDuration returns the duration of a number of seconds, rounded to the nanosecond.
+func secondsToDuration(seconds *big.Float) (time.Duration, error) {
+	nanoseconds := new(big.Float).SetPrec(512).Mul(seconds, big.NewFloat(float64(time.Second)))
+	if nanoseconds.Sign() < 0 {
+		nanoseconds.Sub(nanoseconds, big.NewFloat(0.5))
+	} else {
+		nanoseconds.Add(nanoseconds, big.NewFloat(0.5))
+	}
+	n, _ := nanoseconds.Int(nil)
+	if n == nil || !n.IsInt64() {
+		return 0, fmt.Errorf("duration of %s seconds is out of range", seconds.Text('g', 10))
+	}
+	return time.Duration(n.Int64()), nil
+}
+
+// unifyElements makes sure all elements of a collection have the same type.
+// This is only a concern if the element type is dynamic, so the element types were inferred from the Go values.
+func unifyElements(elementType cty.Type, elems []cty.Value, path cty.Path) ([]cty.Value, cty.Type, error) {
+	if elementType != cty.DynamicPseudoType || len(elems) == 0 {
+		return elems, elementType, nil
+	}
+	types := make([]cty.Type, len(elems))
+	for i := range elems {
+		types[i] = elems[i].Type()
+	}
+	unified, conversions := convert.Unify(types)
+	if unified == cty.NilType {
+		return nil, cty.NilType, path.NewErrorf("all collection elements must have the same type")
+	}
+	out := make([]cty.Value, len(elems))
+	for i := range elems {
+		if conversions[i] == nil {
+			out[i] = elems[i]
+			continue
+		}
+		elem, err := conversions[i](elems[i])
+		if err != nil {
+			return nil, cty.NilType, path.NewError(err)
+		}
+		out[i] = elem
+	}
+	return out, unified, nil
+}
+
+// inferCtyType determines the cty type of a Go value returned where any Tofu type is allowed.
+// Slices and maps become tuples and objects, so that each element can have its own type.
+func inferCtyType(value reflect.Value, opts ConvertOptions, path cty.Path) (cty.Type, error) {
+	t := value.Type()
+	if _, ok := opts.Types[t]; ok || isBytes(t) || t == timeType || t == durationType || isText(t) {
+		return goTypeToCtyType(t, opts, path)
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return cty.DynamicPseudoType, nil
+		}
+		return inferCtyType(value.Elem(), opts, path)
+	case reflect.Slice, reflect.Array:
+		elementTypes := make([]cty.Type, value.Len())
+		for i := range elementTypes {
+			elementType, err := inferCtyType(value.Index(i), opts, path.Index(cty.NumberIntVal(int64(i))))
+			if err != nil {
+				return cty.NilType, err
+			}
+			elementTypes[i] = elementType
+		}
+		return cty.Tuple(elementTypes), nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return cty.NilType, path.NewErrorf("unsupported map key type %s, only string keys are supported", t.Key().String())
+		}
+		attributeTypes := make(map[string]cty.Type, value.Len())
+		for it := value.MapRange(); it.Next(); {
+			key := it.Key().String()
+			attributeType, err := inferCtyType(it.Value(), opts, path.Index(cty.StringVal(key)))
+			if err != nil {
+				return cty.NilType, err
+			}
+			attributeTypes[key] = attributeType
+		}
+		return cty.Object(attributeTypes), nil
+	case reflect.Struct:
+		attributeTypes := make(map[string]cty.Type, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldOpts, err := opts.withField(field)
+			if err != nil {
+				return cty.NilType, path.NewError(err)
+			}
+			name := getTfObjectGoFieldName(field)
+			attributeType, err := inferCtyType(value.Field(i), fieldOpts, path.GetAttr(name))
+			if err != nil {
+				return cty.NilType, err
+			}
+			attributeTypes[name] = attributeType
+		}
+		return cty.Object(attributeTypes), nil
+	default:
+		return goTypeToCtyType(t, opts, path)
+	}
+}
+
+func goTypeToCtyType(t reflect.Type, opts ConvertOptions, path cty.Path) (cty.Type, error) {
+	tfType, err := GoTypeToTFType(t, opts)
+	if err != nil {
+		return cty.NilType, path.NewError(err)
+	}
+	ctyType, err := TFTypeToCtyType(tfType)
+	if err != nil {
+		return cty.NilType, path.NewError(err)
+	}
+	return ctyType, nil
+}
+
+// formatCtyError prefixes the error message with the path to the offending value, if any.
+func formatCtyError(err error) error {
+	var pathErr cty.PathError
+	if !errors.As(err, &pathErr) || len(pathErr.Path) == 0 {
+		return err
+	}
+	var sb strings.Builder
+	for _, step := range pathErr.Path {
+		switch step := step.(type) {
+		case cty.GetAttrStep:
+			sb.WriteString("." + step.Name)
+		case cty.IndexStep:
+			switch step.Key.Type() {
+			case cty.String:
+				fmt.Fprintf(&sb, "[%q]", step.Key.AsString())
+			case cty.Number:
+				fmt.Fprintf(&sb, "[%s]", step.Key.AsBigFloat().Text('f', -1))
+			}
+		}
+	}
+	return fmt.Errorf("%s: %w", strings.TrimPrefix(sb.String(), "."), err)
+}
@@ -0,0 +1,629 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type testPoint struct {
+	X, Y  int
+	Label string `tf:"name"`
+}
+
+type testTagged struct {
+	Raw     []byte        `tf:"raw,raw"`
+	Encoded []byte        `tf:"encoded"`
+	Seconds time.Duration `tf:"seconds,seconds"`
+	Nested  *testPoint
+}
+
+type testBadTag struct {
+	A int `tf:"a,hex"`
+}
+
+// testCelsius is converted with a custom conversion, like the types registered with tofu.RegisterType.
+type testCelsius float64
+
+// testMarshalOnly can only be converted to a string, and testUnmarshalOnly from a string.
+type testMarshalOnly struct{}
+
+func (testMarshalOnly) MarshalText() ([]byte, error) { return []byte("marshaled"), nil }
+
+type testUnmarshalOnly struct{}
+
+func (*testUnmarshalOnly) UnmarshalText([]byte) error { return nil }
+
+var testTypes = TypeRegistry{
+	reflect.TypeFor[testCelsius](): &TypeConverter{
+		Type: tftypes.String,
+		ToTF: func(value any) (any, error) {
+			return fmt.Sprintf("%gC", value.(testCelsius)), nil
+		},
+		FromTF: func(value any) (any, error) {
+			var c testCelsius
+			if _, err := fmt.Sscanf(value.(string), "%gC", &c); err != nil {
+				return nil, err
+			}
+			return c, nil
+		},
+	},
+}
+
+func TestGoTypeToTFType(t *testing.T) {
+	tests := []struct {
+		goType reflect.Type
+		opts   ConvertOptions
+		want   tftypes.Type
+		err    string
+	}{
+		{goType: reflect.TypeFor[string](), want: tftypes.String},
+		{goType: reflect.TypeFor[bool](), want: tftypes.Bool},
+		{goType: reflect.TypeFor[int](), want: tftypes.Number},
+		{goType: reflect.TypeFor[int8](), want: tftypes.Number},
+		{goType: reflect.TypeFor[uint64](), want: tftypes.Number},
+		{goType: reflect.TypeFor[float32](), want: tftypes.Number},
+		{goType: reflect.TypeFor[*string](), want: tftypes.String},
+		{goType: reflect.TypeFor[any](), want: tftypes.DynamicPseudoType},
+		{goType: reflect.TypeFor[[]string](), want: tftypes.List{ElementType: tftypes.String}},
+		{goType: reflect.TypeFor[[]byte](), want: tftypes.String},
+		{goType: reflect.TypeFor[map[string]int](), want: tftypes.Map{ElementType: tftypes.Number}},
+		{goType: reflect.TypeFor[time.Time](), want: tftypes.String},
+		{goType: reflect.TypeFor[time.Duration](), want: tftypes.String},
+		{goType: reflect.TypeFor[time.Duration](), opts: ConvertOptions{DurationFormat: "seconds"}, want: tftypes.Number},
+		{goType: reflect.TypeFor[netip.Addr](), want: tftypes.String},
+		{goType: reflect.TypeFor[*netip.Prefix](), want: tftypes.String},
+		{goType: reflect.TypeFor[testCelsius](), opts: ConvertOptions{Types: testTypes}, want: tftypes.String},
+		{goType: reflect.TypeFor[testCelsius](), want: tftypes.Number},
+		{
+			goType: reflect.TypeFor[testPoint](),
+			want: tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+				"x":    tftypes.Number,
+				"y":    tftypes.Number,
+				"name": tftypes.String,
+			}},
+		},
+		{
+			goType: reflect.TypeFor[testTagged](),
+			want: tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+				"raw":     tftypes.String,
+				"encoded": tftypes.String,
+				"seconds": tftypes.Number,
+				"nested": tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+					"x":    tftypes.Number,
+					"y":    tftypes.Number,
+					"name": tftypes.String,
+				}},
+			}},
+		},
+		{goType: reflect.TypeFor[error](), err: "unsupported interface type error"},
+		{goType: reflect.TypeFor[map[int]string](), err: "unsupported map key type int"},
+		{goType: reflect.TypeFor[chan int](), err: "unsupported type chan int"},
+		{goType: reflect.TypeFor[[]func()](), err: "unsupported type func()"},
+		{goType: reflect.TypeFor[testBadTag](), err: `unsupported tf tag option "hex"`},
+	}
+	for _, test := range tests {
+		t.Run(test.goType.String(), func(t *testing.T) {
+			got, err := GoTypeToTFType(test.goType, test.opts)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(test.want) {
+				t.Fatalf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckText(t *testing.T) {
+	tests := []struct {
+		name   string
+		goType reflect.Type
+		iface  reflect.Type
+		err    string
+	}{
+		{name: "argument", goType: reflect.TypeFor[netip.Addr](), iface: textUnmarshalerType},
+		{name: "result", goType: reflect.TypeFor[netip.Addr](), iface: textMarshalerType},
+		{name: "not text", goType: reflect.TypeFor[testPoint](), iface: textUnmarshalerType},
+		{name: "marshal only argument", goType: reflect.TypeFor[testMarshalOnly](), iface: textUnmarshalerType, err: "type main.testMarshalOnly doesn't implement encoding.TextUnmarshaler"},
+		{name: "marshal only result", goType: reflect.TypeFor[testMarshalOnly](), iface: textMarshalerType},
+		{name: "unmarshal only argument", goType: reflect.TypeFor[*testUnmarshalOnly](), iface: textUnmarshalerType},
+		{name: "unmarshal only result", goType: reflect.TypeFor[*testUnmarshalOnly](), iface: textMarshalerType, err: "type main.testUnmarshalOnly doesn't implement encoding.TextMarshaler"},
+		{name: "nested", goType: reflect.TypeFor[struct{ Values map[string][]testMarshalOnly }](), iface: textUnmarshalerType, err: "field Values: type main.testMarshalOnly"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkText(test.goType, test.iface, ConvertOptions{})
+			if test.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("got error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+// TestConversionRoundTrip converts Go values to protocol values and back, like function arguments and results.
+func TestConversionRoundTrip(t *testing.T) {
+	str := "papaya"
+	number := 42
+	tests := []struct {
+		name  string
+		value any
+		opts  ConvertOptions
+		// dynamic converts the value as an any value, instead of a value of its type.
+		dynamic bool
+		// want is the value converted back, if different.
+		want any
+	}{
+		{name: "string", value: "papaya"},
+		{name: "empty string", value: ""},
+		{name: "bool", value: true},
+		{name: "int", value: -3},
+		{name: "int64", value: int64(math.MaxInt64)},
+		{name: "uint64", value: uint64(math.MaxUint64)},
+		{name: "int8", value: int8(math.MinInt8)},
+		{name: "float64", value: 1.5},
+		{name: "float32", value: float32(0.25)},
+		{name: "string pointer", value: &str},
+		{name: "nil pointer", value: (*int)(nil)},
+		{name: "slice", value: []string{"a", "b"}},
+		{name: "empty slice", value: []int{}},
+		{name: "slice of pointers", value: []*int{&number, nil}},
+		{name: "map", value: map[string]int{"a": 1, "b": 2}},
+		{name: "empty map", value: map[string]bool{}},
+		// Go nil slices and maps are empty, rather than null.
+		{name: "nil slice", value: []int(nil), want: []int{}},
+		{name: "nil map", value: map[string]int(nil), want: map[string]int{}},
+		{name: "normalized string", value: "e\u0301", want: "\u00e9"},
+		{name: "infinity", value: math.Inf(-1)},
+		{name: "min duration", value: time.Duration(math.MinInt64)},
+		{name: "min duration seconds", value: time.Duration(math.MinInt64), opts: ConvertOptions{DurationFormat: "seconds"}},
+		{name: "precise duration seconds", value: time.Duration(8804871523044163584), opts: ConvertOptions{DurationFormat: "seconds"}},
+		{name: "nested", value: map[string][]map[string]float64{"a": {{"b": 0.5}}}},
+		{name: "struct", value: testPoint{X: 1, Y: -2, Label: "p"}},
+		{name: "struct pointer", value: &testPoint{X: 1}},
+		{name: "bytes", value: []byte{0, 1, 255}},
+		{name: "raw bytes", value: []byte("papaya"), opts: ConvertOptions{BytesEncoding: "raw"}},
+		{name: "field options", value: testTagged{Raw: []byte("a"), Encoded: []byte{255}, Seconds: 90 * time.Second, Nested: &testPoint{Y: 1}}},
+		{name: "time", value: time.Date(2024, 6, 1, 12, 30, 0, 500, time.UTC)},
+		{name: "duration", value: 90 * time.Minute},
+		{name: "duration seconds", value: 1500 * time.Millisecond, opts: ConvertOptions{DurationFormat: "seconds"}},
+		{name: "text", value: netip.MustParseAddr("10.0.0.1")},
+		{name: "text pointer", value: ptr(netip.MustParsePrefix("10.0.0.0/8"))},
+		{name: "custom type", value: testCelsius(21.5), opts: ConvertOptions{Types: testTypes}},
+		{name: "any string", dynamic: true, value: any("papaya")},
+		{name: "any number", dynamic: true, value: any(3), want: any(3.0)},
+		{name: "any slice", dynamic: true, value: any([]any{1, "a", true}), want: any([]any{1.0, "a", true})},
+		{name: "any map", dynamic: true, value: any(map[string]any{"a": []int{1}, "b": nil}), want: any(map[string]any{"a": []any{1.0}, "b": nil})},
+		{name: "any struct", dynamic: true, value: any(testPoint{X: 1}), want: any(map[string]any{"x": 1.0, "y": 0.0, "name": ""})},
+		{name: "any time", dynamic: true, value: any(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)), want: any("2024-06-01T00:00:00Z")},
+		{name: "any bytes", dynamic: true, value: any([]byte("a")), want: any("YQ==")},
+		// Elements of lists of any have the same type, like in Tofu.
+		{name: "slice of any", value: []any{1, "a"}, want: []any{"1", "a"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			goType := reflect.TypeOf(test.value)
+			if test.dynamic {
+				goType = reflect.TypeFor[any]()
+			}
+			tfType, err := GoTypeToTFType(goType, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, err := GoToProto(tfType, test.value, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ProtoToGo(tfType, goType, value, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := test.value
+			if test.want != nil {
+				want = test.want
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestGoToCty(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctyType cty.Type
+		value   any
+		opts    ConvertOptions
+		want    cty.Value
+		err     string
+	}{
+		{name: "nil", ctyType: cty.String, value: nil, want: cty.NullVal(cty.String)},
+		{name: "nil any", ctyType: cty.DynamicPseudoType, value: nil, want: cty.NullVal(cty.DynamicPseudoType)},
+		{name: "set", ctyType: cty.Set(cty.String), value: []string{"b", "a", "b"}, want: cty.SetVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")})},
+		{name: "empty set", ctyType: cty.Set(cty.Number), value: []int{}, want: cty.SetValEmpty(cty.Number)},
+		{name: "array", ctyType: cty.List(cty.Number), value: [2]int{1, 2}, want: cty.ListVal([]cty.Value{cty.NumberIntVal(1), cty.NumberIntVal(2)})},
+		{name: "tuple", ctyType: cty.Tuple([]cty.Type{cty.String, cty.Number}), value: []any{"a", 1}, want: cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.NumberIntVal(1)})},
+		{name: "map to object", ctyType: cty.Object(map[string]cty.Type{"a": cty.Number, "b": cty.String}), value: map[string]int{"a": 1}, want: cty.ObjectVal(map[string]cty.Value{"a": cty.NumberIntVal(1), "b": cty.NullVal(cty.String)})},
+		{name: "unified list", ctyType: cty.List(cty.DynamicPseudoType), value: []any{1, "a"}, want: cty.ListVal([]cty.Value{cty.StringVal("1"), cty.StringVal("a")})},
+		{name: "unified map", ctyType: cty.Map(cty.DynamicPseudoType), value: map[string]any{"a": true}, want: cty.MapVal(map[string]cty.Value{"a": cty.True})},
+		{name: "empty dynamic list", ctyType: cty.List(cty.DynamicPseudoType), value: []any{}, want: cty.ListValEmpty(cty.DynamicPseudoType)},
+		{name: "inferred pointer", ctyType: cty.DynamicPseudoType, value: []*int{nil}, want: cty.TupleVal([]cty.Value{cty.NullVal(cty.DynamicPseudoType)})},
+		{name: "time range", ctyType: cty.String, value: time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC), err: "year outside of range"},
+		{name: "NaN", ctyType: cty.Number, value: math.NaN(), err: "NaN can't be represented as a number"},
+		{name: "invalid raw bytes", ctyType: cty.String, value: []byte{255}, opts: ConvertOptions{BytesEncoding: "raw"}, err: "not valid UTF-8"},
+		{name: "not a string", ctyType: cty.String, value: 1, err: "expected string, got int"},
+		{name: "not a bool", ctyType: cty.Bool, value: "true", err: "expected bool, got string"},
+		{name: "not a number", ctyType: cty.Number, value: "1", err: "expected number, got string"},
+		{name: "not a slice", ctyType: cty.List(cty.String), value: "a", err: "expected slice, got string"},
+		{name: "tuple length", ctyType: cty.Tuple([]cty.Type{cty.String}), value: []string{"a", "b"}, err: "expected 1 elements, got 2"},
+		{name: "not a map", ctyType: cty.Map(cty.String), value: []string{}, err: "expected map, got []string"},
+		{name: "not a struct", ctyType: cty.Object(map[string]cty.Type{}), value: 1, err: "expected struct, got int"},
+		{name: "normalized map keys", ctyType: cty.Map(cty.Number), value: map[string]int{"\u00e9": 1, "e\u0301": 2}, err: "duplicate map key \"\u00e9\" once normalized"},
+		{name: "mixed elements", ctyType: cty.List(cty.DynamicPseudoType), value: []any{1, []int{}}, err: "all collection elements must have the same type"},
+		{name: "nested error path", ctyType: cty.Map(cty.List(cty.Number)), value: map[string][]float64{"a": {math.NaN()}}, err: `["a"][0]: NaN`},
+		{name: "unsupported inferred type", ctyType: cty.DynamicPseudoType, value: make(chan int), err: "unsupported type chan int"},
+		{name: "unsupported inferred map key", ctyType: cty.DynamicPseudoType, value: map[int]int{}, err: "unsupported map key type int"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := GoToCty(test.ctyType, test.value, test.opts)
+			if test.err != "" {
+				if err == nil || !strings.Contains(formatCtyError(err).Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(formatCtyError(err))
+			}
+			if !got.RawEquals(test.want) {
+				t.Fatalf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCtyToGo(t *testing.T) {
+	tests := []struct {
+		name   string
+		goType reflect.Type
+		value  cty.Value
+		opts   ConvertOptions
+		want   any
+		err    string
+	}{
+		{name: "null", goType: reflect.TypeFor[*string](), value: cty.NullVal(cty.String), want: (*string)(nil)},
+		{name: "null value", goType: reflect.TypeFor[int](), value: cty.NullVal(cty.Number), want: 0},
+		{name: "null any", goType: reflect.TypeFor[any](), value: cty.NullVal(cty.DynamicPseudoType), want: nil},
+		{name: "marked", goType: reflect.TypeFor[string](), value: cty.StringVal("secret").Mark("sensitive"), want: "secret"},
+		{name: "set", goType: reflect.TypeFor[[]string](), value: cty.SetVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}), want: []string{"a", "b"}},
+		{name: "tuple", goType: reflect.TypeFor[[]int](), value: cty.TupleVal([]cty.Value{cty.NumberIntVal(1)}), want: []int{1}},
+		{name: "object to map", goType: reflect.TypeFor[map[string]string](), value: cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("b")}), want: map[string]string{"a": "b"}},
+		{name: "any set", goType: reflect.TypeFor[any](), value: cty.SetVal([]cty.Value{cty.True}), want: []any{true}},
+		{name: "unknown", goType: reflect.TypeFor[string](), value: cty.UnknownVal(cty.String), err: "value must be known"},
+		{name: "nested unknown", goType: reflect.TypeFor[any](), value: cty.ListVal([]cty.Value{cty.UnknownVal(cty.String)}), err: "[0]: value must be known"},
+		{name: "fraction", goType: reflect.TypeFor[int](), value: cty.NumberFloatVal(1.5), err: "must be a whole number"},
+		{name: "negative uint", goType: reflect.TypeFor[uint](), value: cty.NumberIntVal(-1), err: "must be a whole number, between 0 and"},
+		{name: "not a bool", goType: reflect.TypeFor[bool](), value: cty.StringVal("true"), err: "bool value is required"},
+		{name: "not a list", goType: reflect.TypeFor[[]string](), value: cty.StringVal("a"), err: "list required"},
+		{name: "not a map", goType: reflect.TypeFor[map[string]string](), value: cty.StringVal("a"), err: "map required"},
+		{name: "not an object", goType: reflect.TypeFor[testPoint](), value: cty.StringVal("a"), err: "object required"},
+		{name: "missing field", goType: reflect.TypeFor[testPoint](), value: cty.ObjectVal(map[string]cty.Value{"x": cty.NumberIntVal(1)}), err: "missing object field y"},
+		{name: "map key", goType: reflect.TypeFor[map[int]string](), value: cty.MapValEmpty(cty.String), err: "unsupported map key type int"},
+		{name: "timestamp type", goType: reflect.TypeFor[time.Time](), value: cty.NumberIntVal(1), err: "string required"},
+		{name: "invalid timestamp", goType: reflect.TypeFor[time.Time](), value: cty.StringVal("yesterday"), err: `invalid RFC 3339 timestamp "yesterday"`},
+		{name: "invalid duration", goType: reflect.TypeFor[time.Duration](), value: cty.StringVal("1 day"), err: `invalid duration "1 day"`},
+		{name: "seconds range", goType: reflect.TypeFor[time.Duration](), value: cty.NumberFloatVal(1e10), opts: ConvertOptions{DurationFormat: "seconds"}, err: "out of range"},
+		{name: "seconds type", goType: reflect.TypeFor[time.Duration](), value: cty.StringVal("1s"), opts: ConvertOptions{DurationFormat: "seconds"}, err: "number required"},
+		{name: "invalid base64", goType: reflect.TypeFor[[]byte](), value: cty.StringVal("!"), err: "invalid base64 string"},
+		{name: "invalid text", goType: reflect.TypeFor[netip.Addr](), value: cty.StringVal("10.0.0"), err: "ParseAddr"},
+		{name: "custom type error", goType: reflect.TypeFor[testCelsius](), value: cty.StringVal("hot"), opts: ConvertOptions{Types: testTypes}, err: "invalid syntax"},
+		{name: "unsupported", goType: reflect.TypeFor[chan int](), value: cty.StringVal("a"), err: "unsupported type chan int"},
+		{name: "error path", goType: reflect.TypeFor[map[string][]testPoint](), value: cty.ObjectVal(map[string]cty.Value{"a": cty.TupleVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{"x": cty.StringVal("a"), "y": cty.NumberIntVal(1), "name": cty.StringVal("")})})}), err: `["a"][0].x: `},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := CtyToGo(test.goType, test.value, test.opts)
+			if test.err != "" {
+				if err == nil || !strings.Contains(formatCtyError(err).Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(formatCtyError(err))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTypeRoundTrip(t *testing.T) {
+	tests := []string{
+		"string",
+		"number",
+		"bool",
+		"any",
+		"list(string)",
+		"set(number)",
+		"map(bool)",
+		"tuple([string, number, any])",
+		"object({ name = string, tags = map(string) })",
+		"object({ name = string, port = optional(number) })",
+		"list(object({ items = set(tuple([bool])) }))",
+	}
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			tfType, err := ParseTypeExpr(test)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctyType, err := TFTypeToCtyType(tfType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := CtyTypeToTFType(ctyType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tfType) {
+				t.Fatalf("got %s, want %s", got, tfType)
+			}
+		})
+	}
+
+	if _, err := ParseTypeExpr("list("); err == nil {
+		t.Error("expected an error parsing an invalid type")
+	}
+	if _, err := ParseTypeExpr("lisst(string)"); err == nil {
+		t.Error("expected an error parsing an unknown type")
+	}
+	if _, err := TFTypeToCtyType(nil); err == nil {
+		t.Error("expected an error converting a missing type")
+	}
+}
+
+func TestProtoToCty(t *testing.T) {
+	tests := []struct {
+		name  string
+		value *tfprotov6.DynamicValue
+		want  cty.Value
+	}{
+		{name: "nil", value: nil, want: cty.NullVal(cty.List(cty.String))},
+		{name: "empty", value: &tfprotov6.DynamicValue{}, want: cty.NullVal(cty.List(cty.String))},
+		{name: "json", value: &tfprotov6.DynamicValue{JSON: []byte(`["a"]`)}, want: cty.ListVal([]cty.Value{cty.StringVal("a")})},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ProtoToCty(cty.List(cty.String), test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireEqual(t, got, test.want)
+		})
+	}
+
+	// Dynamic values are encoded along with their type.
+	want := cty.ObjectVal(map[string]cty.Value{"a": cty.TupleVal([]cty.Value{cty.NumberIntVal(1), cty.StringVal("b")})})
+	value, err := CtyToProto(cty.DynamicPseudoType, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ProtoToCty(cty.DynamicPseudoType, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireEqual(t, got, want)
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// fuzzSource generates Go types and values from the bytes of a fuzz input, which are zero once exhausted.
+type fuzzSource struct {
+	data []byte
+	// fields numbers the fields of generated structs, to keep their Tofu names unique.
+	fields int
+}
+
+func (s *fuzzSource) byte() byte {
+	if len(s.data) == 0 {
+		return 0
+	}
+	b := s.data[0]
+	s.data = s.data[1:]
+	return b
+}
+
+func (s *fuzzSource) uint64() uint64 {
+	var n uint64
+	for i := 0; i < 8; i++ {
+		n = n<<8 | uint64(s.byte())
+	}
+	return n
+}
+
+// string generates a string normalized to Unicode NFC, as Tofu normalizes strings.
+func (s *fuzzSource) string() string {
+	b := make([]byte, s.byte()%16)
+	for i := range b {
+		b[i] = s.byte()
+	}
+	return cty.StringVal(string(b)).AsString()
+}
+
+var fuzzScalarTypes = []reflect.Type{
+	reflect.TypeFor[string](),
+	reflect.TypeFor[bool](),
+	reflect.TypeFor[int](),
+	reflect.TypeFor[int8](),
+	reflect.TypeFor[int16](),
+	reflect.TypeFor[int32](),
+	reflect.TypeFor[int64](),
+	reflect.TypeFor[uint](),
+	reflect.TypeFor[uint8](),
+	reflect.TypeFor[uint16](),
+	reflect.TypeFor[uint32](),
+	reflect.TypeFor[uint64](),
+	reflect.TypeFor[float32](),
+	reflect.TypeFor[float64](),
+	reflect.TypeFor[[]byte](),
+	reflect.TypeFor[time.Time](),
+	reflect.TypeFor[time.Duration](),
+	reflect.TypeFor[netip.Addr](),
+}
+
+// goType generates a type, nesting at most depth composite types.
+func (s *fuzzSource) goType(depth int) reflect.Type {
+	choice := int(s.byte())
+	if depth == 0 || choice < len(fuzzScalarTypes) {
+		return fuzzScalarTypes[choice%len(fuzzScalarTypes)]
+	}
+	switch choice % 4 {
+	case 0:
+		elem := s.goType(depth - 1)
+		if elem.Kind() == reflect.Ptr {
+			// Nested pointers are both null when either is nil.
+			return elem
+		}
+		return reflect.PointerTo(elem)
+	case 1:
+		return reflect.SliceOf(s.goType(depth - 1))
+	case 2:
+		return reflect.MapOf(reflect.TypeFor[string](), s.goType(depth-1))
+	default:
+		fields := make([]reflect.StructField, s.byte()%4)
+		for i := range fields {
+			s.fields++
+			fields[i] = reflect.StructField{
+				Name: fmt.Sprintf("F%d", s.fields),
+				Type: s.goType(depth - 1),
+			}
+			if s.byte()%2 == 0 {
+				fields[i].Tag = reflect.StructTag(fmt.Sprintf(`tf:"field_%d"`, s.fields))
+			}
+		}
+		return reflect.StructOf(fields)
+	}
+}
+
+// goValue generates a value of the type.
+func (s *fuzzSource) goValue(t reflect.Type) reflect.Value {
+	value := reflect.New(t).Elem()
+	switch {
+	case t == timeType:
+		value.Set(reflect.ValueOf(time.Unix(int64(s.uint64()), int64(s.uint64()%1e9)).UTC()))
+		return value
+	case t == reflect.TypeFor[netip.Addr]():
+		var addr netip.Addr
+		switch s.byte() % 3 {
+		case 1:
+			addr = netip.AddrFrom4([4]byte{s.byte(), s.byte(), s.byte(), s.byte()})
+		case 2:
+			var b [16]byte
+			for i := range b {
+				b[i] = s.byte()
+			}
+			addr = netip.AddrFrom16(b)
+		}
+		value.Set(reflect.ValueOf(addr))
+		return value
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		value.SetString(s.string())
+	case reflect.Bool:
+		value.SetBool(s.byte()%2 == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.SetInt(int64(s.uint64()) >> (64 - t.Bits()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value.SetUint(s.uint64() >> (64 - t.Bits()))
+	case reflect.Float32:
+		value.SetFloat(float64(math.Float32frombits(uint32(s.uint64()))))
+	case reflect.Float64:
+		value.SetFloat(math.Float64frombits(s.uint64()))
+	case reflect.Ptr:
+		if s.byte()%4 != 0 {
+			value.Set(s.goValue(t.Elem()).Addr())
+		}
+	case reflect.Slice:
+		n := int(s.byte() % 4)
+		value.Set(reflect.MakeSlice(t, n, n))
+		for i := 0; i < n; i++ {
+			value.Index(i).Set(s.goValue(t.Elem()))
+		}
+	case reflect.Map:
+		value.Set(reflect.MakeMap(t))
+		for n := s.byte() % 4; n > 0; n-- {
+			value.SetMapIndex(reflect.ValueOf(s.string()), s.goValue(t.Elem()))
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			value.Field(i).Set(s.goValue(t.Field(i).Type))
+		}
+	}
+	return value
+}
+
+// FuzzConversionRoundTrip converts generated Go values to protocol values and back, which must give the same values.
+func FuzzConversionRoundTrip(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte("papaya"))
+	f.Add([]byte{0xff, 0x03, 0xff, 0x01, 0x02, 0x01, 0x02, 0x03})
+	f.Add([]byte{0xfe, 0x02, 0xfd, 0x11, 0x00, 0x22, 0x01, 'a', 0x03, 0x05, 0x10, 0x20, 0x30, 0x40})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s := &fuzzSource{data: data}
+		var opts ConvertOptions
+		if s.byte()%2 == 1 {
+			opts.DurationFormat = "seconds"
+		}
+		goType := s.goType(3)
+		value := s.goValue(goType)
+
+		tfType, err := GoTypeToTFType(goType, opts)
+		if err != nil {
+			t.Fatalf("%s: %s", goType, err)
+		}
+		proto, err := GoToProto(tfType, value.Interface(), opts)
+		if err != nil {
+			for _, unrepresentable := range []string{"NaN can't be represented", "year outside of range"} {
+				if strings.Contains(err.Error(), unrepresentable) {
+					t.Skip(err)
+				}
+			}
+			t.Fatalf("%s: %#v: %s", goType, value, err)
+		}
+		got, err := ProtoToGo(tfType, goType, proto, opts)
+		if err != nil {
+			t.Fatalf("%s: %#v: %s", goType, value, err)
+		}
+		if !reflect.DeepEqual(got, value.Interface()) {
+			t.Fatalf("%s: got %#v, want %#v", goType, got, value)
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DataSource is a data source implemented by Go code.
+type DataSource struct {
+	Schema *tfprotov6.Schema
+	Read   func(config *tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, []*tfprotov6.Diagnostic)
+}
+
+// GoFunctionToDataSource converts a function like func DataFoo(in FooInput) (FooOutput, error) to a data source.
+// The fields of the input struct become configurable attributes, required unless they are pointers,
+// and the fields of the output struct become computed attributes.
+func GoFunctionToDataSource(fn reflect.Value, opts ConvertOptions) (*DataSource, error) {
+	fnType := fn.Type()
+	if fnType.NumIn() != 1 || fnType.NumOut() != 2 || fnType.Out(1) != reflect.TypeFor[error]() {
+		return nil, fmt.Errorf("data source functions must have the signature func(Input) (Output, error)")
+	}
+	inputType, outputType := fnType.In(0), fnType.Out(0)
+
+	inputAttributes, err := GoStructToSchemaAttributes(inputType, opts, false)
+	if err == nil {
+		err = checkText(inputType, textUnmarshalerType, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("input: %w", err)
+	}
+	outputAttributes, err := GoStructToSchemaAttributes(outputType, opts, true)
+	if err == nil {
+		err = checkText(outputType, textMarshalerType, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("output: %w", err)
+	}
+	attributes, err := mergeSchemaAttributes(inputAttributes, outputAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &tfprotov6.Schema{
+		Block: &tfprotov6.SchemaBlock{
+			Attributes: attributes,
+		},
+	}
+	ctyType, err := TFTypeToCtyType(schema.ValueType())
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataSource{
+		Schema: schema,
+		Read: func(config *tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, []*tfprotov6.Diagnostic) {
+			configValue, err := ProtoToCty(ctyType, config)
+			if err != nil {
+				return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+					Severity: tfprotov6.DiagnosticSeverityError,
+					Summary:  "Invalid data source configuration",
+					Detail:   formatCtyError(err).Error(),
+				}}
+			}
+
+			input, err := CtyToGo(inputType, attributesValue(configValue, inputAttributes), opts)
+			if err != nil {
+				return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+					Severity: tfprotov6.DiagnosticSeverityError,
+					Summary:  "Invalid data source configuration",
+					Detail:   formatCtyError(err).Error(),
+				}}
+			}
+
+			results := fn.Call([]reflect.Value{reflect.ValueOf(input)})
+			if err, _ := results[1].Interface().(error); err != nil {
+				return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+					Severity: tfprotov6.DiagnosticSeverityError,
+					Summary:  "Failed to read data source",
+					Detail:   err.Error(),
+				}}
+			}
+
+			state, err := mergeObjectValue(ctyType, configValue, results[0].Interface(), outputAttributes, opts)
+			if err != nil {
+				return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+					Severity: tfprotov6.DiagnosticSeverityError,
+					Summary:  "Invalid data source result",
+					Detail:   formatCtyError(err).Error(),
+				}}
+			}
+			return state, nil
+		},
+	}, nil
+}
+
+// GoStructToSchemaAttributes derives schema attributes from the fields of a struct, named like object attributes.
+// Attributes are either computed, when computed is set or the field has the computed tag option,
+// or configurable and required unless the field is a pointer.
+func GoStructToSchemaAttributes(t reflect.Type, opts ConvertOptions, computed bool) ([]*tfprotov6.SchemaAttribute, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unsupported type %s, must be a struct", t)
+	}
+
+	var attributes []*tfprotov6.SchemaAttribute
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldOpts, err := opts.withField(field)
+		if err != nil {
+			return nil, err
+		}
+		fieldType, err := GoTypeToTFType(field.Type, fieldOpts)
+		if err != nil {
+			return nil, err
+		}
+		_, tagOpts, _ := strings.Cut(field.Tag.Get("tf"), ",")
+		fieldComputed := computed || slices.Contains(strings.Split(tagOpts, ","), "computed")
+		attributes = append(attributes, &tfprotov6.SchemaAttribute{
+			Name:     getTfObjectGoFieldName(field),
+			Type:     fieldType,
+			Required: !fieldComputed && field.Type.Kind() != reflect.Ptr,
+			Optional: !fieldComputed && field.Type.Kind() == reflect.Ptr,
+			Computed: fieldComputed,
+		})
+	}
+	return attributes, nil
+}
+
+// mergeSchemaAttributes combines attribute lists, which must not share names, sorted by name.
+func mergeSchemaAttributes(lists ...[]*tfprotov6.SchemaAttribute) ([]*tfprotov6.SchemaAttribute, error) {
+	var attributes []*tfprotov6.SchemaAttribute
+	names := map[string]bool{}
+	for _, list := range lists {
+		for _, attribute := range list {
+			if names[attribute.Name] {
+				return nil, fmt.Errorf("attribute %s is defined more than once", attribute.Name)
+			}
+			names[attribute.Name] = true
+			attributes = append(attributes, attribute)
+		}
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].Name < attributes[j].Name
+	})
+	return attributes, nil
+}
+
+// attributesValue returns the object made of the given attributes of an object value.
+func attributesValue(value cty.Value, attributes []*tfprotov6.SchemaAttribute) cty.Value {
+	if len(attributes) == 0 {
+		return cty.EmptyObjectVal
+	}
+	values := make(map[string]cty.Value, len(attributes))
+	for _, attribute := range attributes {
+		values[attribute.Name] = value.GetAttr(attribute.Name)
+	}
+	return cty.ObjectVal(values)
+}
+
+// mergeObjectValue sets the given attributes of an object value from the Go value of a struct holding them.
+func mergeObjectValue(ctyType cty.Type, value cty.Value, goValue any, attributes []*tfprotov6.SchemaAttribute, opts ConvertOptions) (*tfprotov6.DynamicValue, error) {
+	goType := make(map[string]cty.Type, len(attributes))
+	for _, attribute := range attributes {
+		goType[attribute.Name] = ctyType.AttributeType(attribute.Name)
+	}
+	goCtyValue, err := GoToCty(cty.Object(goType), goValue, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	values := value.AsValueMap()
+	if values == nil {
+		values = map[string]cty.Value{}
+	}
+	for name := range goType {
+		values[name] = goCtyValue.GetAttr(name)
+	}
+	return CtyToProto(ctyType, cty.ObjectVal(values))
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+)
+
+// docsCommand generates a Markdown page per function of a source file, in the style of the registry docs, e.g.:
+//
+//	terraform-provider-go docs lib.go lib_test.go
+//
+// The pages describe the signatures of the functions along with their doc comments,
+// and include the Example functions of the Go files as example usage.
+func docsCommand(args []string) error {
+	flags := flag.NewFlagSet("docs", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: terraform-provider-go docs [-settings JSON] [-out DIR] FILE [EXAMPLE FILES...]")
+		flags.PrintDefaults()
+	}
+	settings := flags.String("settings", "", "the settings of the provider, as JSON")
+	out := flags.String("out", filepath.Join("docs", "functions"), "the directory to write the pages to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return errors.New("missing file")
+	}
+
+	provider, err := configureFile(flags.Arg(0), *settings)
+	if err != nil {
+		return err
+	}
+	examples := map[string][]*goExample{}
+	for _, path := range flags.Args() {
+		if filepath.Ext(path) != ".go" {
+			continue
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := goExamples(path, string(source), examples); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	for name, fn := range provider.dynamicFunctions {
+		page := functionDoc(name, &fn.Function, examples[name])
+		if err := os.WriteFile(filepath.Join(*out, name+".md"), []byte(page), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// goExample is an Example function of the Go code.
+type goExample struct {
+	// Suffix is the suffix of the example name, after the underscore.
+	Suffix string
+	// Code is the body of the example, including its output comment.
+	Code string
+}
+
+// goExamples adds the Example functions of the Go code to examples, by the name of the function they're about,
+// e.g. ExampleHello and ExampleHello_twice are examples of hello.
+func goExamples(path, code string, examples map[string][]*goExample) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, code, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || fn.Body == nil {
+			continue
+		}
+		name, ok := cutExportPrefix(fn.Name.Name, "Example")
+		if !ok {
+			continue
+		}
+		name, suffix, _ := strings.Cut(name, "_")
+
+		body := code[fset.Position(fn.Body.Lbrace).Offset+1 : fset.Position(fn.Body.Rbrace).Offset]
+		var lines []string
+		for _, line := range strings.Split(strings.Trim(body, "\n"), "\n") {
+			lines = append(lines, strings.TrimPrefix(line, "\t"))
+		}
+		examples[GoNameToTFName(name)] = append(examples[GoNameToTFName(name)], &goExample{
+			Suffix: suffix,
+			Code:   strings.Join(lines, "\n"),
+		})
+	}
+	return nil
+}
+
+// functionDoc returns the Markdown page of a function.
+func functionDoc(name string, fn *tfprotov6.Function, examples []*goExample) string {
+	summary := fn.Summary
+	if summary == "" {
+		summary, _, _ = strings.Cut(fn.Description, "\n\n")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "---\npage_title: %q\nsubcategory: \"\"\ndescription: |-\n", name+" function - "+providerTypeName)
+	fmt.Fprintf(&sb, "  %s\n---\n\n", strings.ReplaceAll(summary, "\n", "\n  "))
+	fmt.Fprintf(&sb, "# function: %s\n\n", name)
+	if fn.DeprecationMessage != "" {
+		fmt.Fprintf(&sb, "~> **Deprecated:** %s\n\n", fn.DeprecationMessage)
+	}
+	if fn.Description != "" {
+		fmt.Fprintf(&sb, "%s\n\n", fn.Description)
+	}
+
+	if len(examples) > 0 {
+		sb.WriteString("## Example Usage\n\n")
+		sort.Slice(examples, func(i, j int) bool {
+			return examples[i].Suffix < examples[j].Suffix
+		})
+		for _, example := range examples {
+			fmt.Fprintf(&sb, "```go\n%s\n```\n\n", example.Code)
+		}
+	}
+
+	sb.WriteString("## Signature\n\n")
+	fmt.Fprintf(&sb, "```text\n%s\n```\n\n", formatSignature(name, fn))
+
+	params := fn.Parameters
+	if fn.VariadicParameter != nil {
+		params = append(params[:len(params):len(params)], fn.VariadicParameter)
+	}
+	if len(params) > 0 {
+		sb.WriteString("## Arguments\n\n")
+		for i, param := range params {
+			paramName := param.Name
+			if paramName == "" {
+				paramName = fmt.Sprintf("arg%d", i+1)
+			}
+			fmt.Fprintf(&sb, "1. `%s` (%s", paramName, formatType(param.Type))
+			if param == fn.VariadicParameter {
+				sb.WriteString(", variadic")
+			}
+			if param.AllowNullValue {
+				sb.WriteString(", nullable")
+			}
+			sb.WriteString(")")
+			if param.Description != "" {
+				fmt.Fprintf(&sb, " %s", param.Description)
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	fmt.Fprintf(&sb, "## Return Type\n\n`%s`\n", formatType(fn.Return.Type))
+	return sb.String()
+}
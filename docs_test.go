@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDocsCommand(t *testing.T) {
+	t.Setenv(libraryEnv, "")
+	out := t.TempDir()
+	dir := filepath.Join("testdata", "docs")
+	if err := docsCommand([]string{"-out", out, filepath.Join(dir, "lib.go"), filepath.Join(dir, "lib_test.go")}); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join(dir, "functions")
+	if *update {
+		if err := os.RemoveAll(golden); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(golden, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages, err := os.ReadDir(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range pages {
+		got, err := os.ReadFile(filepath.Join(out, page.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		requireGolden(t, filepath.Join(golden, page.Name()), got)
+	}
+	want, err := os.ReadDir(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != len(want) {
+		t.Errorf("got %d pages, want %d", len(pages), len(want))
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/zclconf/go-cty/cty"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// ManagedEphemeralResource is an ephemeral resource implemented by Go code.
+type ManagedEphemeralResource struct {
+	Schema   *tfprotov6.Schema
+	resource EphemeralResource
+	goType   reflect.Type
+	ctyType  cty.Type
+	opts     ConvertOptions
+}
+
+// NewManagedEphemeralResource returns the ephemeral resource of an ephemeral resource registered by the Go code.
+// Its attributes are derived from the fields of the result struct like for managed resources.
+func NewManagedEphemeralResource(registered *RegisteredEphemeralResource, opts ConvertOptions) (*ManagedEphemeralResource, error) {
+	attributes, err := GoStructToSchemaAttributes(registered.ResultType, opts, false)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err = mergeSchemaAttributes(attributes)
+	if err != nil {
+		return nil, err
+	}
+	// Values are converted both ways, e.g. from the configuration and to the result.
+	for _, iface := range []reflect.Type{textUnmarshalerType, textMarshalerType} {
+		if err := checkText(registered.ResultType, iface, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	schema := &tfprotov6.Schema{
+		Block: &tfprotov6.SchemaBlock{
+			Attributes: attributes,
+		},
+	}
+	ctyType, err := TFTypeToCtyType(schema.ValueType())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ManagedEphemeralResource{
+		Schema:   schema,
+		resource: registered.EphemeralResource,
+		goType:   registered.ResultType,
+		ctyType:  ctyType,
+		opts:     opts,
+	}, nil
+}
+
+// Open opens the resource, and returns its result along with the private data identifying it in Renew and Close,
+// which is the result itself as Tofu keeps it in memory only.
+func (r *ManagedEphemeralResource) Open(config *tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, []byte, time.Time, []*tfprotov6.Diagnostic) {
+	configValue, err := ProtoToCty(r.ctyType, config)
+	if err != nil {
+		return nil, nil, time.Time{}, resourceDiagnostics("Invalid ephemeral resource configuration", formatCtyError(err))
+	}
+	if !configValue.IsWhollyKnown() {
+		// The resource can't be opened yet, so its result is unknown.
+		out, err := CtyToProto(r.ctyType, cty.UnknownVal(r.ctyType))
+		if err != nil {
+			return nil, nil, time.Time{}, resourceDiagnostics("Invalid ephemeral resource configuration", err)
+		}
+		return out, nil, time.Time{}, nil
+	}
+	goConfig, err := CtyToGo(r.goType, configValue, r.opts)
+	if err != nil {
+		return nil, nil, time.Time{}, resourceDiagnostics("Invalid ephemeral resource configuration", formatCtyError(err))
+	}
+
+	goResult, renewAt, err := r.resource.Open(goConfig)
+	if err != nil {
+		return nil, nil, time.Time{}, resourceDiagnostics("Failed to open ephemeral resource", err)
+	}
+	if t := reflect.TypeOf(goResult); t != r.goType {
+		return nil, nil, time.Time{}, resourceDiagnostics("Invalid ephemeral resource result", fmt.Errorf("expected a result of type %s, got %s", r.goType, t))
+	}
+	result, err := GoToCty(r.ctyType, goResult, r.opts)
+	if err != nil {
+		return nil, nil, time.Time{}, resourceDiagnostics("Invalid ephemeral resource result", formatCtyError(err))
+	}
+
+	private, err := ctymsgpack.Marshal(result, r.ctyType)
+	if err != nil {
+		return nil, nil, time.Time{}, resourceDiagnostics("Invalid ephemeral resource result", formatCtyError(err))
+	}
+	out, err := CtyToProto(r.ctyType, result)
+	if err != nil {
+		return nil, nil, time.Time{}, resourceDiagnostics("Invalid ephemeral resource result", err)
+	}
+	return out, private, renewAt, nil
+}
+
+// Renew renews the resource identified by the private data returned by Open.
+func (r *ManagedEphemeralResource) Renew(private []byte) (time.Time, []*tfprotov6.Diagnostic) {
+	result, err := r.privateToGo(private)
+	if err != nil {
+		return time.Time{}, resourceDiagnostics("Invalid ephemeral resource private data", err)
+	}
+	renewAt, err := r.resource.Renew(result)
+	if err != nil {
+		return time.Time{}, resourceDiagnostics("Failed to renew ephemeral resource", err)
+	}
+	return renewAt, nil
+}
+
+// Close closes the resource identified by the private data returned by Open.
+func (r *ManagedEphemeralResource) Close(private []byte) []*tfprotov6.Diagnostic {
+	result, err := r.privateToGo(private)
+	if err != nil {
+		return resourceDiagnostics("Invalid ephemeral resource private data", err)
+	}
+	if err := r.resource.Close(result); err != nil {
+		return resourceDiagnostics("Failed to close ephemeral resource", err)
+	}
+	return nil
+}
+
+func (r *ManagedEphemeralResource) privateToGo(private []byte) (any, error) {
+	value, err := ctymsgpack.Unmarshal(private, r.ctyType)
+	if err != nil {
+		return nil, formatCtyError(err)
+	}
+	result, err := CtyToGo(r.goType, value, r.opts)
+	if err != nil {
+		return nil, formatCtyError(err)
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// testEphemeralLibrary opens tokens, whose status is returned by the status function.
+const testEphemeralLibrary = `package lib
+
+import (
+	"errors"
+	"time"
+	"tofu"
+)
+
+type Token struct {
+	Subject string
+	Value   string ` + "`tf:\"value,computed\"`" + `
+}
+
+var tokens = map[string]string{}
+
+type tokenResource struct{}
+
+func (tokenResource) Open(config any) (any, time.Time, error) {
+	token := config.(Token)
+	if token.Subject == "" {
+		return nil, time.Time{}, errors.New("empty subject")
+	}
+	token.Value = "token-" + token.Subject
+	tokens[token.Value] = "open"
+	return token, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), nil
+}
+
+func (tokenResource) Renew(result any) (time.Time, error) {
+	tokens[result.(Token).Value] = "renewed"
+	return time.Date(2030, 1, 1, 1, 0, 0, 0, time.UTC), nil
+}
+
+func (tokenResource) Close(result any) error {
+	value := result.(Token).Value
+	if tokens[value] == "closed" {
+		return errors.New(value + " is already closed")
+	}
+	tokens[value] = "closed"
+	return nil
+}
+
+func Status(value string) string { return tokens[value] }
+
+func init() {
+	tofu.RegisterEphemeralResource("token", Token{}, tokenResource{})
+}
+`
+
+func TestEphemeralResourceLifecycle(t *testing.T) {
+	p := startProvider(t, testEphemeralLibrary)
+
+	result, private, renewAt, diags := p.openEphemeral("go_token", map[string]cty.Value{"subject": cty.StringVal("deploy")})
+	requireNoErrors(t, diags)
+	requireEqual(t, result, cty.ObjectVal(map[string]cty.Value{"subject": cty.StringVal("deploy"), "value": cty.StringVal("token-deploy")}))
+	if want := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC); !renewAt.Equal(want) {
+		t.Fatalf("got renewal at %s, want %s", renewAt, want)
+	}
+	requireEqual(t, p.mustCall("status", cty.StringVal("token-deploy")), cty.StringVal("open"))
+
+	renewAt, diags = p.renewEphemeral("go_token", private)
+	requireNoErrors(t, diags)
+	if want := time.Date(2030, 1, 1, 1, 0, 0, 0, time.UTC); !renewAt.Equal(want) {
+		t.Fatalf("got renewal at %s, want %s", renewAt, want)
+	}
+	requireEqual(t, p.mustCall("status", cty.StringVal("token-deploy")), cty.StringVal("renewed"))
+
+	requireNoErrors(t, p.closeEphemeral("go_token", private))
+	requireEqual(t, p.mustCall("status", cty.StringVal("token-deploy")), cty.StringVal("closed"))
+	requireDiagnostic(t, p.closeEphemeral("go_token", private), tfprotov6.DiagnosticSeverityError,
+		"Failed to close ephemeral resource", "token-deploy is already closed")
+}
+
+func TestOpenEphemeralResourceUnknown(t *testing.T) {
+	p := startProvider(t, testEphemeralLibrary)
+	// The resource can't be opened until its configuration is known.
+	result, private, _, diags := p.openEphemeral("go_token", map[string]cty.Value{"subject": cty.UnknownVal(cty.String)})
+	requireNoErrors(t, diags)
+	requireEqual(t, result, cty.UnknownVal(p.ephemeralResourceType("go_token")))
+	if private != nil {
+		t.Fatalf("got private data %q, want none", private)
+	}
+}
+
+func TestEphemeralResourceErrors(t *testing.T) {
+	p := startProvider(t, testEphemeralLibrary)
+
+	_, _, _, diags := p.openEphemeral("go_token", map[string]cty.Value{"subject": cty.StringVal("")})
+	requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, "Failed to open ephemeral resource", "empty subject")
+
+	_, diags = p.renewEphemeral("go_token", []byte("papaya"))
+	requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, "Invalid ephemeral resource private data", "")
+	diags = p.closeEphemeral("go_token", []byte("papaya"))
+	requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, "Invalid ephemeral resource private data", "")
+}
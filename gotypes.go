@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/traefik/yaegi/interp"
+	"github.com/traefik/yaegi/stdlib"
+	"github.com/traefik/yaegi/stdlib/generic"
+)
+
+// checkGoTypes type-checks the parsed Go code against the packages available to the interpreter,
+// and returns the errors found, which are soft when the interpreter accepts the code anyway, like unused variables.
+func checkGoTypes(fset *token.FileSet, file *ast.File) []types.Error {
+	var errs []types.Error
+	config := &types.Config{
+		Importer: newGoImporter(fset, stdlib.Symbols, tofuSymbols(TypeRegistry{}, ResourceRegistry{}, EphemeralResourceRegistry{}), logSymbols()),
+		Error: func(err error) {
+			if typeErr, ok := err.(types.Error); ok {
+				errs = append(errs, typeErr)
+			}
+		},
+	}
+	config.Check("lib", fset, []*ast.File{file}, nil)
+	return errs
+}
+
+// goImporter imports the packages available to the Go code from the symbols given to the interpreter,
+// as the export data of the standard library isn't available without the Go toolchain.
+// The generic packages, like slices, are type-checked from the source code the interpreter compiles them from.
+type goImporter struct {
+	fset *token.FileSet
+	// symbols and names are the symbols and the name of each package, by import path.
+	symbols map[string]map[string]reflect.Value
+	names   map[string]string
+	// sources are the parsed generic packages, by import path.
+	sources map[string]*ast.File
+
+	packages map[string]*types.Package
+	// types are the types converted so far, named types being converted before their underlying type and methods.
+	types map[reflect.Type]types.Type
+}
+
+func newGoImporter(fset *token.FileSet, exports ...interp.Exports) *goImporter {
+	importer := &goImporter{
+		fset:     fset,
+		symbols:  map[string]map[string]reflect.Value{},
+		names:    map[string]string{},
+		sources:  map[string]*ast.File{},
+		packages: map[string]*types.Package{},
+		types:    map[reflect.Type]types.Type{},
+	}
+	for _, packages := range exports {
+		for key, symbols := range packages {
+			importPath := path.Dir(key)
+			if importer.symbols[importPath] == nil {
+				importer.symbols[importPath] = map[string]reflect.Value{}
+			}
+			for name, symbol := range symbols {
+				importer.symbols[importPath][name] = symbol
+			}
+			importer.names[importPath] = path.Base(key)
+		}
+	}
+	for _, source := range generic.Sources {
+		// The sources are valid, and errors are reported when type-checking them anyway.
+		file, _ := parser.ParseFile(fset, "", source, 0)
+		if file != nil {
+			importer.sources[file.Name.Name] = file
+		}
+	}
+	return importer
+}
+
+func (i *goImporter) Import(importPath string) (*types.Package, error) {
+	if pkg := i.packages[importPath]; pkg != nil && pkg.Complete() {
+		return pkg, nil
+	}
+	if file := i.sources[importPath]; file != nil {
+		// The sources use parts of their packages the interpreter doesn't support, which are ignored like it does.
+		config := &types.Config{Importer: i, Error: func(error) {}}
+		pkg, _ := config.Check(importPath, i.fset, []*ast.File{file}, nil)
+		i.packages[importPath] = pkg
+		return pkg, nil
+	}
+	symbols, ok := i.symbols[importPath]
+	if !ok {
+		return nil, fmt.Errorf("package %s is not available", importPath)
+	}
+
+	pkg := i.pkg(importPath)
+	for name, symbol := range symbols {
+		// Interface wrappers of the interpreter.
+		if strings.HasPrefix(name, "_") {
+			continue
+		}
+		pkg.Scope().Insert(i.object(pkg, name, symbol))
+	}
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+// pkg returns the package with the import path, which may be created to hold a named type before being imported.
+func (i *goImporter) pkg(importPath string) *types.Package {
+	pkg := i.packages[importPath]
+	if pkg == nil {
+		name := i.names[importPath]
+		if name == "" {
+			name = path.Base(importPath)
+		}
+		pkg = types.NewPackage(importPath, name)
+		i.packages[importPath] = pkg
+	}
+	return pkg
+}
+
+// object converts a symbol of the package, following the conventions of the interpreter:
+// types are nil pointers to them, variables are addressable, and untyped constants are constant.Value.
+func (i *goImporter) object(pkg *types.Package, name string, symbol reflect.Value) types.Object {
+	switch {
+	case symbol.CanAddr():
+		return types.NewVar(token.NoPos, pkg, name, i.typeOf(symbol.Type()))
+	case symbol.Kind() == reflect.Ptr && symbol.IsNil():
+		t := i.typeOf(symbol.Type().Elem())
+		if named, ok := t.(*types.Named); ok && named.Obj().Pkg() == pkg && named.Obj().Name() == name {
+			return named.Obj()
+		}
+		// An alias, like tofu.Type.
+		return types.NewTypeName(token.NoPos, pkg, name, t)
+	case symbol.Kind() == reflect.Func:
+		return types.NewFunc(token.NoPos, pkg, name, i.typeOf(symbol.Type()).(*types.Signature))
+	}
+	if value, ok := symbol.Interface().(constant.Value); ok {
+		return types.NewConst(token.NoPos, pkg, name, untypedConstTypes[value.Kind()], value)
+	}
+	var value constant.Value
+	switch symbol.Kind() {
+	case reflect.Bool:
+		value = constant.MakeBool(symbol.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = constant.MakeInt64(symbol.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		value = constant.MakeUint64(symbol.Uint())
+	case reflect.Float32, reflect.Float64:
+		value = constant.MakeFloat64(symbol.Float())
+	case reflect.String:
+		value = constant.MakeString(symbol.String())
+	default:
+		return types.NewVar(token.NoPos, pkg, name, i.typeOf(symbol.Type()))
+	}
+	return types.NewConst(token.NoPos, pkg, name, i.typeOf(symbol.Type()), value)
+}
+
+var untypedConstTypes = map[constant.Kind]types.Type{
+	constant.Bool:    types.Typ[types.UntypedBool],
+	constant.String:  types.Typ[types.UntypedString],
+	constant.Int:     types.Typ[types.UntypedInt],
+	constant.Float:   types.Typ[types.UntypedFloat],
+	constant.Complex: types.Typ[types.UntypedComplex],
+}
+
+var basicTypes = map[reflect.Kind]types.BasicKind{
+	reflect.Bool:          types.Bool,
+	reflect.Int:           types.Int,
+	reflect.Int8:          types.Int8,
+	reflect.Int16:         types.Int16,
+	reflect.Int32:         types.Int32,
+	reflect.Int64:         types.Int64,
+	reflect.Uint:          types.Uint,
+	reflect.Uint8:         types.Uint8,
+	reflect.Uint16:        types.Uint16,
+	reflect.Uint32:        types.Uint32,
+	reflect.Uint64:        types.Uint64,
+	reflect.Uintptr:       types.Uintptr,
+	reflect.Float32:       types.Float32,
+	reflect.Float64:       types.Float64,
+	reflect.Complex64:     types.Complex64,
+	reflect.Complex128:    types.Complex128,
+	reflect.String:        types.String,
+	reflect.UnsafePointer: types.UnsafePointer,
+}
+
+// typeOf converts a Go type to its go/types representation.
+func (i *goImporter) typeOf(t reflect.Type) types.Type {
+	if converted, ok := i.types[t]; ok {
+		return converted
+	}
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			// A predeclared type, like int or error.
+			return types.Universe.Lookup(t.Name()).Type()
+		}
+		obj := types.NewTypeName(token.NoPos, i.pkg(t.PkgPath()), t.Name(), nil)
+		named := types.NewNamed(obj, nil, nil)
+		i.types[t] = named
+		named.SetUnderlying(i.underlying(t))
+		if t.Kind() != reflect.Interface {
+			i.addMethods(named, t)
+		}
+		return named
+	}
+	converted := i.underlying(t)
+	i.types[t] = converted
+	return converted
+}
+
+// underlying converts the structure of a Go type, ignoring its name.
+func (i *goImporter) underlying(t reflect.Type) types.Type {
+	if kind, ok := basicTypes[t.Kind()]; ok {
+		return types.Typ[kind]
+	}
+	switch t.Kind() {
+	case reflect.Array:
+		return types.NewArray(i.typeOf(t.Elem()), int64(t.Len()))
+	case reflect.Slice:
+		return types.NewSlice(i.typeOf(t.Elem()))
+	case reflect.Map:
+		return types.NewMap(i.typeOf(t.Key()), i.typeOf(t.Elem()))
+	case reflect.Ptr:
+		return types.NewPointer(i.typeOf(t.Elem()))
+	case reflect.Chan:
+		dir := types.SendRecv
+		switch t.ChanDir() {
+		case reflect.SendDir:
+			dir = types.SendOnly
+		case reflect.RecvDir:
+			dir = types.RecvOnly
+		}
+		return types.NewChan(dir, i.typeOf(t.Elem()))
+	case reflect.Func:
+		return i.signature(nil, t, 0)
+	case reflect.Struct:
+		var fields []*types.Var
+		var tags []string
+		for n := 0; n < t.NumField(); n++ {
+			field := t.Field(n)
+			// Only unexported fields belong to a package.
+			var pkg *types.Package
+			if field.PkgPath != "" {
+				pkg = i.pkg(field.PkgPath)
+			}
+			fields = append(fields, types.NewField(token.NoPos, pkg, field.Name, i.typeOf(field.Type), field.Anonymous))
+			tags = append(tags, string(field.Tag))
+		}
+		return types.NewStruct(fields, tags)
+	case reflect.Interface:
+		var methods []*types.Func
+		for n := 0; n < t.NumMethod(); n++ {
+			method := t.Method(n)
+			// Unexported methods are left out, as those of the types implementing the interface aren't known.
+			if !method.IsExported() {
+				continue
+			}
+			methods = append(methods, types.NewFunc(token.NoPos, nil, method.Name, i.signature(nil, method.Type, 0)))
+		}
+		return types.NewInterfaceType(methods, nil).Complete()
+	default:
+		return types.Typ[types.Invalid]
+	}
+}
+
+// signature converts a function type, skipping its first parameters which are the receiver of a method.
+func (i *goImporter) signature(recv *types.Var, t reflect.Type, skip int) *types.Signature {
+	var params, results []*types.Var
+	for n := skip; n < t.NumIn(); n++ {
+		params = append(params, types.NewParam(token.NoPos, nil, "", i.typeOf(t.In(n))))
+	}
+	for n := 0; n < t.NumOut(); n++ {
+		results = append(results, types.NewParam(token.NoPos, nil, "", i.typeOf(t.Out(n))))
+	}
+	return types.NewSignatureType(recv, nil, nil, types.NewTuple(params...), types.NewTuple(results...), t.IsVariadic())
+}
+
+// addMethods adds the exported methods of the Go type to its named type, with a pointer receiver
+// for those only in the method set of the pointer type.
+func (i *goImporter) addMethods(named *types.Named, t reflect.Type) {
+	values := map[string]bool{}
+	for n := 0; n < t.NumMethod(); n++ {
+		method := t.Method(n)
+		values[method.Name] = true
+		recv := types.NewVar(token.NoPos, named.Obj().Pkg(), "", named)
+		named.AddMethod(types.NewFunc(token.NoPos, named.Obj().Pkg(), method.Name, i.signature(recv, method.Type, 1)))
+	}
+	ptr := reflect.PointerTo(t)
+	for n := 0; n < ptr.NumMethod(); n++ {
+		method := ptr.Method(n)
+		if values[method.Name] {
+			continue
+		}
+		recv := types.NewVar(token.NoPos, named.Obj().Pkg(), "", types.NewPointer(named))
+		named.AddMethod(types.NewFunc(token.NoPos, named.Obj().Pkg(), method.Name, i.signature(recv, method.Type, 1)))
+	}
+}
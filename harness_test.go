@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the tests")
+
+// requireGolden fails the test unless got is the content of the golden file, which is written instead with -update.
+func requireGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s is outdated, run go test -update to update it, got:\n%s", path, got)
+	}
+}
+
+// testProvider runs the provider in-process, and talks to it through the protocol like Tofu does.
+// Its requests are made with ctx, which tests can replace to capture the logs.
+type testProvider struct {
+	t      *testing.T
+	ctx    context.Context
+	server tfprotov6.ProviderServer
+}
+
+// startProvider returns a provider serving the Go code as its static library, if any,
+// like the library given by the TOFU_PROVIDER_GO_LIBRARY environment variable.
+func startProvider(t *testing.T, library string) *testProvider {
+	t.Helper()
+	var goLibrary *GoLibrary
+	if library != "" {
+		var diags []*tfprotov6.Diagnostic
+		goLibrary, diags = LoadGo(library, ConvertOptions{})
+		requireNoErrors(t, diags)
+	}
+	provider, err := newProvider(goLibrary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testProvider{t: t, ctx: context.Background(), server: provider}
+}
+
+// configuredProvider returns a provider configured with the attributes, failing the test on errors.
+func configuredProvider(t *testing.T, config map[string]cty.Value) *testProvider {
+	t.Helper()
+	p := startProvider(t, "")
+	requireNoErrors(t, p.configure(config))
+	return p
+}
+
+// validate validates the provider configuration made of the attributes, the others being null.
+func (p *testProvider) validate(config map[string]cty.Value) []*tfprotov6.Diagnostic {
+	p.t.Helper()
+	resp, err := p.server.ValidateProviderConfig(p.ctx, &tfprotov6.ValidateProviderConfigRequest{Config: p.providerConfig(config)})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return resp.Diagnostics
+}
+
+// configure validates and configures the provider with the attributes, the others being null,
+// and returns the diagnostics of both.
+func (p *testProvider) configure(config map[string]cty.Value) []*tfprotov6.Diagnostic {
+	p.t.Helper()
+	diags := p.validate(config)
+	if hasErrors(diags) {
+		return diags
+	}
+	resp, err := p.server.ConfigureProvider(p.ctx, &tfprotov6.ConfigureProviderRequest{Config: p.providerConfig(config)})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return append(diags, resp.Diagnostics...)
+}
+
+// providerConfig encodes the attributes as a provider configuration, the others being null.
+func (p *testProvider) providerConfig(config map[string]cty.Value) *tfprotov6.DynamicValue {
+	p.t.Helper()
+	schema, err := p.server.GetProviderSchema(p.ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return p.dynamicValue(schema.Provider, config)
+}
+
+// call calls the function with the arguments, encoded with the types of its parameters, and decodes its result.
+func (p *testProvider) call(name string, args ...cty.Value) (cty.Value, *tfprotov6.FunctionError) {
+	p.t.Helper()
+	ctx := p.ctx
+	functions, err := p.server.GetFunctions(ctx, &tfprotov6.GetFunctionsRequest{})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	fn, ok := functions.Functions[name]
+	if !ok {
+		p.t.Fatalf("unknown function %s", name)
+	}
+
+	var arguments []*tfprotov6.DynamicValue
+	for i, arg := range args {
+		param := fn.VariadicParameter
+		if i < len(fn.Parameters) {
+			param = fn.Parameters[i]
+		}
+		ctyType, err := TFTypeToCtyType(param.Type)
+		if err != nil {
+			p.t.Fatal(err)
+		}
+		argument, err := CtyToProto(ctyType, arg)
+		if err != nil {
+			p.t.Fatalf("argument %d: %s", i, formatCtyError(err))
+		}
+		arguments = append(arguments, argument)
+	}
+
+	resp, err := p.server.CallFunction(ctx, &tfprotov6.CallFunctionRequest{Name: name, Arguments: arguments})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if resp.Error != nil {
+		return cty.NilVal, resp.Error
+	}
+	returnType, err := TFTypeToCtyType(fn.Return.Type)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	result, err := ProtoToCty(returnType, resp.Result)
+	if err != nil {
+		p.t.Fatalf("result: %s", formatCtyError(err))
+	}
+	return result, nil
+}
+
+// mustCall calls the function, failing the test if it returns an error.
+func (p *testProvider) mustCall(name string, args ...cty.Value) cty.Value {
+	p.t.Helper()
+	result, funcErr := p.call(name, args...)
+	if funcErr != nil {
+		p.t.Fatalf("%s: %s", name, funcErr.Text)
+	}
+	return result
+}
+
+// readDataSource reads the data source configured with the attributes, the others being null, and returns its state.
+func (p *testProvider) readDataSource(typeName string, config map[string]cty.Value) (cty.Value, []*tfprotov6.Diagnostic) {
+	p.t.Helper()
+	ctx := p.ctx
+	schema, err := p.server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	dataSourceSchema, ok := schema.DataSourceSchemas[typeName]
+	if !ok {
+		p.t.Fatalf("unknown data source %s", typeName)
+	}
+
+	resp, err := p.server.ReadDataSource(ctx, &tfprotov6.ReadDataSourceRequest{
+		TypeName: typeName,
+		Config:   p.dynamicValue(dataSourceSchema, config),
+	})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if resp.State == nil {
+		return cty.NilVal, resp.Diagnostics
+	}
+	ctyType, err := TFTypeToCtyType(dataSourceSchema.ValueType())
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	state, err := ProtoToCty(ctyType, resp.State)
+	if err != nil {
+		p.t.Fatalf("state: %s", formatCtyError(err))
+	}
+	return state, resp.Diagnostics
+}
+
+// resourceType returns the type of the state of the resource.
+func (p *testProvider) resourceType(typeName string) cty.Type {
+	p.t.Helper()
+	schema, err := p.server.GetProviderSchema(p.ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	resourceSchema, ok := schema.ResourceSchemas[typeName]
+	if !ok {
+		p.t.Fatalf("unknown resource %s", typeName)
+	}
+	ctyType, err := TFTypeToCtyType(resourceSchema.ValueType())
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return ctyType
+}
+
+// planResource plans the change of the resource from its prior state, cty.NilVal when creating it,
+// to the configuration made of the attributes, the others being null, or to nothing if config is nil.
+// Like Tofu, the proposed state has the prior values of the computed attributes which aren't configured.
+func (p *testProvider) planResource(typeName string, prior cty.Value, config map[string]cty.Value) (cty.Value, []*tftypes.AttributePath, []*tfprotov6.Diagnostic) {
+	p.t.Helper()
+	ctyType := p.resourceType(typeName)
+	if prior == cty.NilVal {
+		prior = cty.NullVal(ctyType)
+	}
+	configValue, proposed := cty.NullVal(ctyType), cty.NullVal(ctyType)
+	if config != nil {
+		values := map[string]cty.Value{}
+		for name, attributeType := range ctyType.AttributeTypes() {
+			values[name] = cty.NullVal(attributeType)
+		}
+		for name, value := range config {
+			if _, ok := values[name]; !ok {
+				p.t.Fatalf("unknown attribute %s", name)
+			}
+			values[name] = value
+		}
+		configValue = cty.ObjectVal(values)
+		if !prior.IsNull() {
+			for name := range values {
+				if values[name].IsNull() {
+					values[name] = prior.GetAttr(name)
+				}
+			}
+		}
+		proposed = cty.ObjectVal(values)
+	}
+
+	resp, err := p.server.PlanResourceChange(p.ctx, &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         typeName,
+		PriorState:       p.encode(ctyType, prior),
+		ProposedNewState: p.encode(ctyType, proposed),
+		Config:           p.encode(ctyType, configValue),
+	})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if resp.PlannedState == nil {
+		return cty.NilVal, nil, resp.Diagnostics
+	}
+	return p.decode(ctyType, resp.PlannedState), resp.RequiresReplace, resp.Diagnostics
+}
+
+// applyResource applies the planned state of the resource, null to delete it, and returns its new state.
+func (p *testProvider) applyResource(typeName string, prior, planned cty.Value) (cty.Value, []*tfprotov6.Diagnostic) {
+	p.t.Helper()
+	ctyType := p.resourceType(typeName)
+	if prior == cty.NilVal {
+		prior = cty.NullVal(ctyType)
+	}
+	resp, err := p.server.ApplyResourceChange(p.ctx, &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     typeName,
+		PriorState:   p.encode(ctyType, prior),
+		PlannedState: p.encode(ctyType, planned),
+	})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if resp.NewState == nil {
+		return cty.NilVal, resp.Diagnostics
+	}
+	return p.decode(ctyType, resp.NewState), resp.Diagnostics
+}
+
+// importResource imports the resource with the given ID, and returns its state.
+func (p *testProvider) importResource(typeName, id string) (cty.Value, []*tfprotov6.Diagnostic) {
+	p.t.Helper()
+	resp, err := p.server.ImportResourceState(p.ctx, &tfprotov6.ImportResourceStateRequest{TypeName: typeName, ID: id})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if len(resp.ImportedResources) != 1 {
+		return cty.NilVal, resp.Diagnostics
+	}
+	return p.decode(p.resourceType(typeName), resp.ImportedResources[0].State), resp.Diagnostics
+}
+
+// upgradeResource upgrades the state of the resource stored as JSON, and returns the upgraded state.
+func (p *testProvider) upgradeResource(typeName, state string) (cty.Value, []*tfprotov6.Diagnostic) {
+	p.t.Helper()
+	resp, err := p.server.UpgradeResourceState(p.ctx, &tfprotov6.UpgradeResourceStateRequest{
+		TypeName: typeName,
+		RawState: &tfprotov6.RawState{JSON: []byte(state)},
+	})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if resp.UpgradedState == nil {
+		return cty.NilVal, resp.Diagnostics
+	}
+	return p.decode(p.resourceType(typeName), resp.UpgradedState), resp.Diagnostics
+}
+
+// ephemeralServer returns the server of the provider, which is still a separate interface for ephemeral resources.
+func (p *testProvider) ephemeralServer() tfprotov6.ProviderServerWithEphemeralResources {
+	return p.server.(tfprotov6.ProviderServerWithEphemeralResources)
+}
+
+// ephemeralResourceType returns the type of the configuration and result of the ephemeral resource.
+func (p *testProvider) ephemeralResourceType(typeName string) cty.Type {
+	p.t.Helper()
+	schema, err := p.server.GetProviderSchema(p.ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	resourceSchema, ok := schema.EphemeralResourceSchemas[typeName]
+	if !ok {
+		p.t.Fatalf("unknown ephemeral resource %s", typeName)
+	}
+	ctyType, err := TFTypeToCtyType(resourceSchema.ValueType())
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return ctyType
+}
+
+// openEphemeral opens the ephemeral resource configured with the attributes, the others being null,
+// and returns its result, along with the private data identifying it and when to renew it.
+func (p *testProvider) openEphemeral(typeName string, config map[string]cty.Value) (cty.Value, []byte, time.Time, []*tfprotov6.Diagnostic) {
+	p.t.Helper()
+	ctyType := p.ephemeralResourceType(typeName)
+	values := map[string]cty.Value{}
+	for name, attributeType := range ctyType.AttributeTypes() {
+		values[name] = cty.NullVal(attributeType)
+	}
+	for name, value := range config {
+		if _, ok := values[name]; !ok {
+			p.t.Fatalf("unknown attribute %s", name)
+		}
+		values[name] = value
+	}
+	resp, err := p.ephemeralServer().OpenEphemeralResource(p.ctx, &tfprotov6.OpenEphemeralResourceRequest{
+		TypeName: typeName,
+		Config:   p.encode(ctyType, cty.ObjectVal(values)),
+	})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if resp.Result == nil {
+		return cty.NilVal, nil, time.Time{}, resp.Diagnostics
+	}
+	return p.decode(ctyType, resp.Result), resp.Private, resp.RenewAt, resp.Diagnostics
+}
+
+// renewEphemeral renews the ephemeral resource identified by its private data, and returns when to renew it next.
+func (p *testProvider) renewEphemeral(typeName string, private []byte) (time.Time, []*tfprotov6.Diagnostic) {
+	p.t.Helper()
+	resp, err := p.ephemeralServer().RenewEphemeralResource(p.ctx, &tfprotov6.RenewEphemeralResourceRequest{TypeName: typeName, Private: private})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return resp.RenewAt, resp.Diagnostics
+}
+
+// closeEphemeral closes the ephemeral resource identified by its private data.
+func (p *testProvider) closeEphemeral(typeName string, private []byte) []*tfprotov6.Diagnostic {
+	p.t.Helper()
+	resp, err := p.ephemeralServer().CloseEphemeralResource(p.ctx, &tfprotov6.CloseEphemeralResourceRequest{TypeName: typeName, Private: private})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return resp.Diagnostics
+}
+
+// encode encodes a value of the type, which may be unknown.
+func (p *testProvider) encode(ctyType cty.Type, value cty.Value) *tfprotov6.DynamicValue {
+	p.t.Helper()
+	out, err := CtyToProto(ctyType, value)
+	if err != nil {
+		p.t.Fatal(formatCtyError(err))
+	}
+	return out
+}
+
+// decode decodes a value of the type.
+func (p *testProvider) decode(ctyType cty.Type, value *tfprotov6.DynamicValue) cty.Value {
+	p.t.Helper()
+	out, err := ProtoToCty(ctyType, value)
+	if err != nil {
+		p.t.Fatal(formatCtyError(err))
+	}
+	return out
+}
+
+// dynamicValue encodes the attributes as a value of the schema, the others being null.
+func (p *testProvider) dynamicValue(schema *tfprotov6.Schema, attributes map[string]cty.Value) *tfprotov6.DynamicValue {
+	p.t.Helper()
+	ctyType, err := TFTypeToCtyType(schema.ValueType())
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	values := map[string]cty.Value{}
+	for name, attributeType := range ctyType.AttributeTypes() {
+		values[name] = cty.NullVal(attributeType)
+	}
+	for name, value := range attributes {
+		if _, ok := values[name]; !ok {
+			p.t.Fatalf("unknown attribute %s", name)
+		}
+		values[name] = value
+	}
+	value, err := CtyToProto(ctyType, cty.ObjectVal(values))
+	if err != nil {
+		p.t.Fatal(formatCtyError(err))
+	}
+	return value
+}
+
+// requireNoDiagnostics fails the test if there are any diagnostics, including warnings.
+func requireNoDiagnostics(t *testing.T, diags []*tfprotov6.Diagnostic) {
+	t.Helper()
+	for _, diag := range diags {
+		t.Fatalf("unexpected %s: %s: %s", diag.Severity, diag.Summary, diag.Detail)
+	}
+}
+
+// requireNoErrors fails the test if any of the diagnostics is an error.
+func requireNoErrors(t *testing.T, diags []*tfprotov6.Diagnostic) {
+	t.Helper()
+	for _, diag := range diags {
+		if diag.Severity == tfprotov6.DiagnosticSeverityError {
+			t.Fatalf("unexpected error: %s: %s", diag.Summary, diag.Detail)
+		}
+	}
+}
+
+// requireDiagnostic fails the test unless one of the diagnostics has the severity and summary,
+// and a detail containing the given text.
+func requireDiagnostic(t *testing.T, diags []*tfprotov6.Diagnostic, severity tfprotov6.DiagnosticSeverity, summary, detail string) {
+	t.Helper()
+	for _, diag := range diags {
+		if diag.Severity == severity && diag.Summary == summary && strings.Contains(diag.Detail, detail) {
+			return
+		}
+	}
+	var got []string
+	for _, diag := range diags {
+		got = append(got, diag.Severity.String()+": "+diag.Summary+": "+diag.Detail)
+	}
+	t.Fatalf("missing %s diagnostic %q with detail containing %q, got:\n%s", severity, summary, detail, strings.Join(got, "\n"))
+}
+
+// requireEqual fails the test unless the values are equal, including their types.
+func requireEqual(t *testing.T, got, want cty.Value) {
+	t.Helper()
+	if !got.RawEquals(want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/traefik/yaegi/interp"
+)
+
+// calls holds the calls in progress, keyed by the ID of the goroutine running them.
+// The Go code logs and warns through package-level functions, which have no other way to find their call
+// without serializing the calls of the Go code.
+// Goroutines started by the Go code aren't part of any call, so their logs and warnings are dropped.
+var calls sync.Map
+
+// lastCallID numbers the calls, so that the logs of concurrent calls can be told apart.
+var lastCallID atomic.Int64
+
+// call is a call of the Go code by an RPC.
+type call struct {
+	ctx context.Context
+	id  int64
+	// goroutine is the ID of the goroutine running the call, or 0 if it couldn't be parsed.
+	goroutine int64
+	warnings  []*tfprotov6.Diagnostic
+}
+
+// beginCall makes the code run by the current goroutine log to ctx, along with the given fields and a call ID,
+// until the call ends.
+func beginCall(ctx context.Context, fields map[string]any) *call {
+	c := &call{id: lastCallID.Add(1)}
+	ctx = tflog.SetField(ctx, "call_id", c.id)
+	for key, value := range fields {
+		ctx = tflog.SetField(ctx, key, value)
+	}
+	c.ctx = ctx
+	if id, ok := goroutineID(); ok {
+		c.goroutine = id
+		calls.Store(id, c)
+	}
+	return c
+}
+
+func (c *call) end() {
+	if c.goroutine != 0 {
+		calls.Delete(c.goroutine)
+	}
+}
+
+// currentCall returns the call run by the current goroutine, or nil if there's none,
+// e.g. in goroutines started by the Go code.
+func currentCall() *call {
+	id, ok := goroutineID()
+	if !ok {
+		return nil
+	}
+	if c, ok := calls.Load(id); ok {
+		return c.(*call)
+	}
+	return nil
+}
+
+// logContext returns the logging context of the current call.
+// Logs of goroutines started by the Go code are dropped, as they aren't part of any call.
+func logContext() context.Context {
+	if c := currentCall(); c != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+// warn logs a warning of the Go code, and adds it to the diagnostics of the current call.
+// Functions can't return warnings, so theirs are only logged,
+// and the warnings of goroutines started by the Go code are dropped like their logs.
+func warn(summary, detail string) {
+	tflog.Warn(logContext(), summary, map[string]any{"detail": detail})
+	if c := currentCall(); c != nil {
+		c.warnings = append(c.warnings, &tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityWarning,
+			Summary:  summary,
+			Detail:   detail,
+		})
+	}
+}
+
+// goroutineID returns the ID of the current goroutine, parsed from the header of its stack trace, e.g. "goroutine 42 [running]:".
+// It reports false if the header can't be parsed, in which case the current goroutine runs no call.
+//
+// No supported mechanism finds the call otherwise: Go has no goroutine-local storage, the interpreter
+// shares the symbols of the tofu packages between all the calls and has no hook to run before a function,
+// and the functions of the Go code have the signature their author gave them, so they can't be passed a context.
+// It's the only place parsing the stack, which takes a couple of microseconds per log, see BenchmarkGoroutineID.
+func goroutineID() (int64, bool) {
+	var buf [64]byte
+	stack := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	end := bytes.IndexByte(stack, ' ')
+	if end < 0 {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(string(stack[:end]), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// logSymbols returns the "tofu/log" package available to the Go code, which logs through terraform-plugin-log,
+// e.g. log.Printf("resolving %s", name) or log.Debug("resolved", map[string]any{"name": name}).
+// The logs are shown by Tofu with TF_LOG, the Print functions logging at the info level.
+func logSymbols() interp.Exports {
+	return interp.Exports{
+		"tofu/log/log": {
+			"Print": reflect.ValueOf(func(v ...any) {
+				tflog.Info(logContext(), fmt.Sprint(v...))
+			}),
+			"Printf": reflect.ValueOf(func(format string, v ...any) {
+				tflog.Info(logContext(), fmt.Sprintf(format, v...))
+			}),
+			"Println": reflect.ValueOf(func(v ...any) {
+				msg := fmt.Sprintln(v...)
+				tflog.Info(logContext(), msg[:len(msg)-1])
+			}),
+			"Trace": reflect.ValueOf(func(msg string, fields ...map[string]any) {
+				tflog.Trace(logContext(), msg, fields...)
+			}),
+			"Debug": reflect.ValueOf(func(msg string, fields ...map[string]any) {
+				tflog.Debug(logContext(), msg, fields...)
+			}),
+			"Info": reflect.ValueOf(func(msg string, fields ...map[string]any) {
+				tflog.Info(logContext(), msg, fields...)
+			}),
+			"Warn": reflect.ValueOf(func(msg string, fields ...map[string]any) {
+				tflog.Warn(logContext(), msg, fields...)
+			}),
+			"Error": reflect.ValueOf(func(msg string, fields ...map[string]any) {
+				tflog.Error(logContext(), msg, fields...)
+			}),
+		},
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestLogs(t *testing.T) {
+	p := configuredProvider(t, map[string]cty.Value{"go": cty.StringVal(`package lib
+
+import (
+	"tofu"
+	"tofu/log"
+)
+
+func Hello(name string) string {
+	log.Printf("greeting %s", name)
+	log.Debug("greeted", map[string]any{"length": len(name)})
+	if name == "" {
+		tofu.Warn("Empty name", "Greeting nobody.")
+	}
+	done := make(chan bool)
+	go func() {
+		log.Print("from a goroutine")
+		done <- true
+	}()
+	<-done
+	return "Hello, " + name + "!"
+}
+`)})
+	var output bytes.Buffer
+	p.ctx = tflogtest.RootLogger(context.Background(), &output)
+	p.mustCall("hello", cty.StringVal("papaya"))
+	p.mustCall("hello", cty.StringVal(""))
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		level, message string
+		field          string
+	}{
+		{level: "info", message: "greeting papaya"},
+		{level: "debug", message: "greeted", field: "length"},
+		{level: "info", message: "greeting "},
+		{level: "debug", message: "greeted", field: "length"},
+		{level: "warn", message: "Empty name", field: "detail"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d logs, want %d: %v", len(entries), len(want), entries)
+	}
+	callIDs := map[any]bool{}
+	for i, entry := range entries {
+		if entry["@level"] != want[i].level || entry["@message"] != want[i].message {
+			t.Errorf("log %d: got %v %q, want %s %q", i, entry["@level"], entry["@message"], want[i].level, want[i].message)
+		}
+		if _, ok := entry[want[i].field]; want[i].field != "" && !ok {
+			t.Errorf("log %d: missing field %s", i, want[i].field)
+		}
+		if entry["function"] != "hello" {
+			t.Errorf("log %d: got function %v, want hello", i, entry["function"])
+		}
+		callIDs[entry["call_id"]] = true
+	}
+	if len(callIDs) != 2 {
+		t.Errorf("got call IDs %v, want one per call", callIDs)
+	}
+}
+
+func TestGoroutineWarnings(t *testing.T) {
+	p := startProvider(t, `package lib
+
+import "tofu"
+
+type Input struct {
+	Name string
+}
+
+type Output struct {
+	Greeting string
+}
+
+func DataGreeting(in Input) (Output, error) {
+	tofu.Warn("Greeting", "From the call.")
+	done := make(chan bool)
+	go func() {
+		tofu.Warn("Greeting", "From a goroutine.")
+		done <- true
+	}()
+	<-done
+	return Output{Greeting: "Hello, " + in.Name + "!"}, nil
+}
+`)
+	requireNoErrors(t, p.configure(nil))
+	_, diags := p.readDataSource("go_greeting", map[string]cty.Value{"name": cty.StringVal("papaya")})
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want the warning of the call only: %v", len(diags), diags)
+	}
+	requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityWarning, "Greeting", "From the call.")
+}
+
+func TestGoroutineID(t *testing.T) {
+	id, ok := goroutineID()
+	if !ok {
+		t.Fatal("failed to parse the ID of the current goroutine")
+	}
+	other := make(chan int64)
+	go func() {
+		id, _ := goroutineID()
+		other <- id
+	}()
+	if otherID := <-other; otherID == id {
+		t.Errorf("got the same ID %d for two goroutines", id)
+	}
+}
+
+func BenchmarkGoroutineID(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		goroutineID()
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/Shopify/go-lua"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// LuaBackend runs Lua functions using the go-lua virtual machine.
+type LuaBackend struct{}
+
+func (b *LuaBackend) Name() string {
+	return "lua"
+}
+
+func (b *LuaBackend) Description() string {
+	return "Lua source code, returning a table of signatures of the global functions to become provider functions."
+}
+
+func (b *LuaBackend) Load(source string, settings cty.Value, opts ConvertOptions) (Library, []*tfprotov6.Diagnostic) {
+	functions, diags := LuaFunctions(source, settings, opts)
+	if len(diags) > 0 {
+		return nil, diags
+	}
+	return FunctionLibrary(functions), nil
+}
+
+// LuaFunctions loads the Lua code and returns the functions declared in the signature table it returns.
+// The signatures use Tofu type constraints, e.g.:
+//
+//	function hello(name)
+//	  return "Hello, " .. name .. "!"
+//	end
+//
+//	return {
+//	  hello = { params = { "string" }, returns = "string" },
+//	}
+//
+// Functions may fail by raising an error, or by returning nil and an error message as the second value.
+// The settings are available as the settings global.
+func LuaFunctions(code string, settings cty.Value, opts ConvertOptions) (map[string]*Function, []*tfprotov6.Diagnostic) {
+	l := lua.NewState()
+	openLuaLibraries(l)
+
+	goSettings, err := ctyToAny(settings, nil)
+	if err != nil {
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity:  tfprotov6.DiagnosticSeverityError,
+			Summary:   "Invalid settings",
+			Detail:    formatCtyError(err).Error(),
+			Attribute: tftypes.NewAttributePath().WithAttributeName("settings"),
+		}}
+	}
+	pushGo(l, goSettings)
+	l.SetGlobal("settings")
+
+	if err := lua.LoadBuffer(l, code, "=lua", "t"); err != nil {
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Failed to load Lua code",
+			Detail:   err.Error(),
+		}}
+	}
+	if err := l.ProtectedCall(0, 1, 0); err != nil {
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Failed to evaluate Lua code",
+			Detail:   err.Error(),
+		}}
+	}
+	if !l.IsTable(-1) {
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Lua code must return a table of function signatures",
+		}}
+	}
+	signatures, err := luaToGo(l, -1, cty.Map(cty.DynamicPseudoType))
+	if err != nil {
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid Lua function signatures",
+			Detail:   err.Error(),
+		}}
+	}
+	l.Pop(1)
+
+	// The Lua state isn't safe for concurrent use, while functions may be called concurrently.
+	var mu sync.Mutex
+	functions := map[string]*Function{}
+	for name, signature := range signatures.(map[string]any) {
+		l.Global(name)
+		isFunction := l.IsFunction(-1)
+		l.Pop(1)
+		if !isFunction {
+			return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+				Severity: tfprotov6.DiagnosticSeverityError,
+				Summary:  "Invalid Lua function signatures",
+				Detail:   fmt.Sprintf("%s: there is no global function named %s", name, name),
+			}}
+		}
+
+		fn, err := signatureToTFFunction(signature)
+		if err != nil {
+			return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+				Severity: tfprotov6.DiagnosticSeverityError,
+				Summary:  "Invalid Lua function signatures",
+				Detail:   fmt.Sprintf("%s: %s", name, err),
+			}}
+		}
+		returnType, err := TFTypeToCtyType(fn.Return.Type)
+		if err != nil {
+			return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+				Severity: tfprotov6.DiagnosticSeverityError,
+				Summary:  "Invalid Lua function signatures",
+				Detail:   fmt.Sprintf("%s: %s", name, err),
+			}}
+		}
+
+		name := name
+		functions[name] = &Function{
+			Function: *fn,
+			Impl: func(args []*tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, *tfprotov6.FunctionError) {
+				mu.Lock()
+				defer mu.Unlock()
+				defer l.SetTop(l.Top())
+
+				l.Global(name)
+				for i, arg := range args {
+					goArg, err := ProtoToGo(fn.Parameters[i].Type, reflect.TypeFor[any](), arg, opts)
+					if err != nil {
+						argument := int64(i)
+						return nil, &tfprotov6.FunctionError{
+							Text:             err.Error(),
+							FunctionArgument: &argument,
+						}
+					}
+					pushGo(l, goArg)
+				}
+				if err := l.ProtectedCall(len(args), 2, 0); err != nil {
+					return nil, &tfprotov6.FunctionError{
+						Text: err.Error(),
+					}
+				}
+				if !l.IsNil(-1) {
+					msg, _ := l.ToString(-1)
+					return nil, &tfprotov6.FunctionError{
+						Text: msg,
+					}
+				}
+
+				result, err := luaToGo(l, -2, returnType)
+				if err != nil {
+					return nil, &tfprotov6.FunctionError{
+						Text: err.Error(),
+					}
+				}
+				out, err := GoToProto(fn.Return.Type, result, opts)
+				if err != nil {
+					return nil, &tfprotov6.FunctionError{
+						Text: err.Error(),
+					}
+				}
+				return out, nil
+			},
+		}
+	}
+
+	return functions, nil
+}
+
+// openLuaLibraries opens the base, string, table and math libraries, without those accessing the system like io and os,
+// and removes dofile and loadfile from the base library.
+func openLuaLibraries(l *lua.State) {
+	for _, lib := range []lua.RegistryFunction{
+		{Name: "_G", Function: lua.BaseOpen},
+		{Name: "string", Function: lua.StringOpen},
+		{Name: "table", Function: lua.TableOpen},
+		{Name: "math", Function: lua.MathOpen},
+	} {
+		lua.Require(l, lib.Name, lib.Function, true)
+		l.Pop(1)
+	}
+	for _, name := range []string{"dofile", "loadfile"} {
+		l.PushNil()
+		l.SetGlobal(name)
+	}
+}
+
+// pushGo pushes the generic Go representation of a value onto the Lua stack.
+func pushGo(l *lua.State, value any) {
+	switch value := value.(type) {
+	case nil:
+		l.PushNil()
+	case string:
+		l.PushString(value)
+	case bool:
+		l.PushBoolean(value)
+	case float64:
+		l.PushNumber(value)
+	case []any:
+		l.CreateTable(len(value), 0)
+		for i, elem := range value {
+			pushGo(l, elem)
+			l.RawSetInt(-2, i+1)
+		}
+	case map[string]any:
+		l.CreateTable(0, len(value))
+		for key, elem := range value {
+			pushGo(l, elem)
+			l.SetField(-2, key)
+		}
+	default:
+		panic(fmt.Sprintf("unexpected generic value of type %T", value))
+	}
+}
+
+// luaToGo converts the Lua value at the given stack index to its generic Go representation.
+// The expected type decides whether tables are read as sequences or as maps, which Lua doesn't distinguish.
+func luaToGo(l *lua.State, index int, ty cty.Type) (any, error) {
+	if index < 0 {
+		index = l.Top() + index + 1
+	}
+
+	switch l.TypeOf(index) {
+	case lua.TypeNil:
+		return nil, nil
+	case lua.TypeBoolean:
+		return l.ToBoolean(index), nil
+	case lua.TypeNumber:
+		n, _ := l.ToNumber(index)
+		return n, nil
+	case lua.TypeString:
+		s, _ := l.ToString(index)
+		return s, nil
+	case lua.TypeTable:
+		isSequence := ty.IsListType() || ty.IsSetType() || ty.IsTupleType()
+		if ty == cty.DynamicPseudoType {
+			isSequence = l.RawLength(index) > 0
+		}
+
+		if isSequence {
+			out := make([]any, l.RawLength(index))
+			for i := range out {
+				elementType := cty.DynamicPseudoType
+				switch {
+				case ty.IsTupleType() && i < len(ty.TupleElementTypes()):
+					elementType = ty.TupleElementType(i)
+				case ty.IsCollectionType():
+					elementType = ty.ElementType()
+				}
+				l.RawGetInt(index, i+1)
+				elem, err := luaToGo(l, -1, elementType)
+				l.Pop(1)
+				if err != nil {
+					return nil, fmt.Errorf("[%d]: %w", i+1, err)
+				}
+				out[i] = elem
+			}
+			if entries := luaTableEntries(l, index); entries != len(out) {
+				return nil, fmt.Errorf("table mixes a sequence of %d values with other keys", len(out))
+			}
+			return out, nil
+		}
+
+		out := map[string]any{}
+		for l.PushNil(); l.Next(index); l.Pop(1) {
+			if l.TypeOf(-2) != lua.TypeString {
+				typeName := lua.TypeNameOf(l, -2)
+				l.Pop(2)
+				return nil, fmt.Errorf("table keys must be strings, got %s", typeName)
+			}
+			key, _ := l.ToString(-2)
+			elementType := cty.DynamicPseudoType
+			switch {
+			case ty.IsObjectType() && ty.HasAttribute(key):
+				elementType = ty.AttributeType(key)
+			case ty.IsCollectionType():
+				elementType = ty.ElementType()
+			}
+			elem, err := luaToGo(l, -1, elementType)
+			if err != nil {
+				l.Pop(2)
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			out[key] = elem
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported Lua type %s", lua.TypeNameOf(l, index))
+	}
+}
+
+// luaTableEntries returns the number of entries of the table at the given stack index,
+// to check whether a sequence has other keys.
+func luaTableEntries(l *lua.State, index int) int {
+	entries := 0
+	for l.PushNil(); l.Next(index); l.Pop(1) {
+		entries++
+	}
+	return entries
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestLuaLibraries(t *testing.T) {
+	p := configuredProvider(t, map[string]cty.Value{"lua": cty.StringVal(`
+function has(name)
+  return load("return " .. name)() ~= nil
+end
+
+return {
+  has = { params = { "string" }, returns = "bool" },
+}`)})
+
+	tests := map[string]bool{
+		"string.upper": true,
+		"table.concat": true,
+		"math.floor":   true,
+		"pcall":        true,
+		"io":           false,
+		"os":           false,
+		"debug":        false,
+		"package":      false,
+		"require":      false,
+		"dofile":       false,
+		"loadfile":     false,
+	}
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			requireEqual(t, p.mustCall("has", cty.StringVal(name)), cty.BoolVal(want))
+		})
+	}
+}
+
+func TestLuaTables(t *testing.T) {
+	p := configuredProvider(t, map[string]cty.Value{"lua": cty.StringVal(`
+function sequence() return { 1, 2 } end
+function object() return { name = "a" } end
+function mixed() return { 1, 2, name = "a" } end
+function mixed_list() return { 1, 2, name = "a" } end
+
+return {
+  sequence = { params = {}, returns = "any" },
+  object = { params = {}, returns = "any" },
+  mixed = { params = {}, returns = "any" },
+  mixed_list = { params = {}, returns = "list(number)" },
+}`)})
+
+	requireEqual(t, p.mustCall("sequence"), cty.TupleVal([]cty.Value{cty.NumberIntVal(1), cty.NumberIntVal(2)}))
+	requireEqual(t, p.mustCall("object"), cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("a")}))
+	for _, name := range []string{"mixed", "mixed_list"} {
+		t.Run(name, func(t *testing.T) {
+			_, funcErr := p.call(name)
+			if funcErr == nil || !strings.Contains(funcErr.Text, "table mixes a sequence of 2 values with other keys") {
+				t.Fatalf("got error %v, want a mixed table error", funcErr)
+			}
+		})
+	}
+}
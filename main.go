@@ -2,17 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding"
+	"encoding/base64"
 	"errors"
 	"fmt"
-	"math/big"
+	"os"
 	"reflect"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
-	"github.com/hashicorp/terraform-plugin-go/tfprotov6/tf6server"
 	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/traefik/yaegi/interp"
-	"github.com/traefik/yaegi/stdlib"
+	"github.com/zclconf/go-cty/cty"
 )
 
 type Function struct {
@@ -21,10 +24,15 @@ type Function struct {
 }
 
 type FunctionProvider struct {
-	ProviderSchema   *tfprotov6.Schema
-	StaticFunctions  map[string]*Function
-	dynamicFunctions map[string]*Function
-	Configure        func(*tfprotov6.DynamicValue) (map[string]*Function, []*tfprotov6.Diagnostic)
+	ProviderSchema     *tfprotov6.Schema
+	StaticFunctions    map[string]*Function
+	DataSources        map[string]*DataSource
+	Resources          map[string]*ManagedResource
+	EphemeralResources map[string]*ManagedEphemeralResource
+	StaticLibrary      *GoLibrary
+	Backends           []Backend
+	dynamicFunctions   map[string]*Function
+	libraries          []Library
 }
 
 func (f *FunctionProvider) GetMetadata(context.Context, *tfprotov6.GetMetadataRequest) (*tfprotov6.GetMetadataResponse, error) {
@@ -32,10 +40,25 @@ func (f *FunctionProvider) GetMetadata(context.Context, *tfprotov6.GetMetadataRe
 	for name := range f.StaticFunctions {
 		functions = append(functions, tfprotov6.FunctionMetadata{Name: name})
 	}
+	var dataSources []tfprotov6.DataSourceMetadata
+	for name := range f.DataSources {
+		dataSources = append(dataSources, tfprotov6.DataSourceMetadata{TypeName: name})
+	}
+	var resources []tfprotov6.ResourceMetadata
+	for name := range f.Resources {
+		resources = append(resources, tfprotov6.ResourceMetadata{TypeName: name})
+	}
+	var ephemeralResources []tfprotov6.EphemeralResourceMetadata
+	for name := range f.EphemeralResources {
+		ephemeralResources = append(ephemeralResources, tfprotov6.EphemeralResourceMetadata{TypeName: name})
+	}
 
 	return &tfprotov6.GetMetadataResponse{
 		ServerCapabilities: &tfprotov6.ServerCapabilities{GetProviderSchemaOptional: true},
 		Functions:          functions,
+		DataSources:        dataSources,
+		Resources:          resources,
+		EphemeralResources: ephemeralResources,
 	}, nil
 }
 func (f *FunctionProvider) GetProviderSchema(context.Context, *tfprotov6.GetProviderSchemaRequest) (*tfprotov6.GetProviderSchemaResponse, error) {
@@ -43,52 +66,198 @@ func (f *FunctionProvider) GetProviderSchema(context.Context, *tfprotov6.GetProv
 	for name, fn := range f.StaticFunctions {
 		functions[name] = &fn.Function
 	}
+	dataSources := make(map[string]*tfprotov6.Schema)
+	for name, dataSource := range f.DataSources {
+		dataSources[name] = dataSource.Schema
+	}
+	resources := make(map[string]*tfprotov6.Schema)
+	for name, resource := range f.Resources {
+		resources[name] = resource.Schema
+	}
+	ephemeralResources := make(map[string]*tfprotov6.Schema)
+	for name, resource := range f.EphemeralResources {
+		ephemeralResources[name] = resource.Schema
+	}
 
 	return &tfprotov6.GetProviderSchemaResponse{
-		ServerCapabilities: &tfprotov6.ServerCapabilities{GetProviderSchemaOptional: true},
-		Provider:           f.ProviderSchema,
-		Functions:          functions,
+		ServerCapabilities:       &tfprotov6.ServerCapabilities{GetProviderSchemaOptional: true},
+		Provider:                 f.ProviderSchema,
+		Functions:                functions,
+		DataSourceSchemas:        dataSources,
+		ResourceSchemas:          resources,
+		EphemeralResourceSchemas: ephemeralResources,
 	}, nil
 }
 func (f *FunctionProvider) ValidateProviderConfig(ctx context.Context, req *tfprotov6.ValidateProviderConfigRequest) (*tfprotov6.ValidateProviderConfigResponse, error) {
-	// Passthrough
-	return &tfprotov6.ValidateProviderConfigResponse{PreparedConfig: req.Config}, nil
+	return &tfprotov6.ValidateProviderConfigResponse{
+		PreparedConfig: req.Config,
+		Diagnostics:    f.validate(req.Config),
+	}, nil
 }
 func (f *FunctionProvider) ConfigureProvider(ctx context.Context, req *tfprotov6.ConfigureProviderRequest) (*tfprotov6.ConfigureProviderResponse, error) {
-	funcs, diags := f.Configure(req.Config)
-	f.dynamicFunctions = funcs
+	call := beginCall(ctx, nil)
+	defer call.end()
+	funcs, libraries, diags := f.configure(req.Config)
+	closeLibraries(f.libraries)
+	f.dynamicFunctions, f.libraries = funcs, libraries
 	return &tfprotov6.ConfigureProviderResponse{
-		Diagnostics: diags,
+		Diagnostics: append(call.warnings, diags...),
 	}, nil
 }
 func (f *FunctionProvider) StopProvider(context.Context, *tfprotov6.StopProviderRequest) (*tfprotov6.StopProviderResponse, error) {
 	return &tfprotov6.StopProviderResponse{}, nil
 }
-func (f *FunctionProvider) ValidateResourceConfig(context.Context, *tfprotov6.ValidateResourceConfigRequest) (*tfprotov6.ValidateResourceConfigResponse, error) {
-	return nil, errors.New("not supported")
+func (f *FunctionProvider) ValidateResourceConfig(ctx context.Context, req *tfprotov6.ValidateResourceConfigRequest) (*tfprotov6.ValidateResourceConfigResponse, error) {
+	if _, ok := f.Resources[req.TypeName]; !ok {
+		return nil, errors.New("unknown resource " + req.TypeName)
+	}
+	// The configuration is type-checked against the schema by Tofu.
+	return &tfprotov6.ValidateResourceConfigResponse{}, nil
 }
-func (f *FunctionProvider) UpgradeResourceState(context.Context, *tfprotov6.UpgradeResourceStateRequest) (*tfprotov6.UpgradeResourceStateResponse, error) {
-	return nil, errors.New("not supported")
+func (f *FunctionProvider) UpgradeResourceState(ctx context.Context, req *tfprotov6.UpgradeResourceStateRequest) (*tfprotov6.UpgradeResourceStateResponse, error) {
+	resource, ok := f.Resources[req.TypeName]
+	if !ok {
+		return nil, errors.New("unknown resource " + req.TypeName)
+	}
+	state, diags := resource.Upgrade(req.RawState)
+	return &tfprotov6.UpgradeResourceStateResponse{
+		UpgradedState: state,
+		Diagnostics:   diags,
+	}, nil
 }
-func (f *FunctionProvider) ReadResource(context.Context, *tfprotov6.ReadResourceRequest) (*tfprotov6.ReadResourceResponse, error) {
-	return nil, errors.New("not supported")
+func (f *FunctionProvider) ReadResource(ctx context.Context, req *tfprotov6.ReadResourceRequest) (*tfprotov6.ReadResourceResponse, error) {
+	resource, ok := f.Resources[req.TypeName]
+	if !ok {
+		return nil, errors.New("unknown resource " + req.TypeName)
+	}
+	call := beginCall(ctx, map[string]any{"resource": req.TypeName})
+	defer call.end()
+	state, diags := resource.Read(req.CurrentState)
+	return &tfprotov6.ReadResourceResponse{
+		NewState:    state,
+		Diagnostics: append(call.warnings, diags...),
+		Private:     req.Private,
+	}, nil
 }
-func (f *FunctionProvider) PlanResourceChange(context.Context, *tfprotov6.PlanResourceChangeRequest) (*tfprotov6.PlanResourceChangeResponse, error) {
-	return nil, errors.New("not supported")
+func (f *FunctionProvider) PlanResourceChange(ctx context.Context, req *tfprotov6.PlanResourceChangeRequest) (*tfprotov6.PlanResourceChangeResponse, error) {
+	resource, ok := f.Resources[req.TypeName]
+	if !ok {
+		return nil, errors.New("unknown resource " + req.TypeName)
+	}
+	state, requiresReplace, diags := resource.Plan(req.PriorState, req.ProposedNewState)
+	return &tfprotov6.PlanResourceChangeResponse{
+		PlannedState:    state,
+		RequiresReplace: requiresReplace,
+		Diagnostics:     diags,
+		PlannedPrivate:  req.PriorPrivate,
+	}, nil
 }
-func (f *FunctionProvider) ApplyResourceChange(context.Context, *tfprotov6.ApplyResourceChangeRequest) (*tfprotov6.ApplyResourceChangeResponse, error) {
-	return nil, errors.New("not supported")
+func (f *FunctionProvider) ApplyResourceChange(ctx context.Context, req *tfprotov6.ApplyResourceChangeRequest) (*tfprotov6.ApplyResourceChangeResponse, error) {
+	resource, ok := f.Resources[req.TypeName]
+	if !ok {
+		return nil, errors.New("unknown resource " + req.TypeName)
+	}
+	call := beginCall(ctx, map[string]any{"resource": req.TypeName})
+	defer call.end()
+	state, diags := resource.Apply(req.PriorState, req.PlannedState)
+	return &tfprotov6.ApplyResourceChangeResponse{
+		NewState:    state,
+		Diagnostics: append(call.warnings, diags...),
+		Private:     req.PlannedPrivate,
+	}, nil
 }
-func (f *FunctionProvider) ImportResourceState(context.Context, *tfprotov6.ImportResourceStateRequest) (*tfprotov6.ImportResourceStateResponse, error) {
-	return nil, errors.New("not supported")
+func (f *FunctionProvider) ImportResourceState(ctx context.Context, req *tfprotov6.ImportResourceStateRequest) (*tfprotov6.ImportResourceStateResponse, error) {
+	resource, ok := f.Resources[req.TypeName]
+	if !ok {
+		return nil, errors.New("unknown resource " + req.TypeName)
+	}
+	call := beginCall(ctx, map[string]any{"resource": req.TypeName})
+	defer call.end()
+	state, diags := resource.Import(req.ID)
+	if len(diags) > 0 {
+		return &tfprotov6.ImportResourceStateResponse{Diagnostics: append(call.warnings, diags...)}, nil
+	}
+	return &tfprotov6.ImportResourceStateResponse{
+		Diagnostics: call.warnings,
+		ImportedResources: []*tfprotov6.ImportedResource{{
+			TypeName: req.TypeName,
+			State:    state,
+		}},
+	}, nil
 }
-func (f *FunctionProvider) ValidateDataResourceConfig(context.Context, *tfprotov6.ValidateDataResourceConfigRequest) (*tfprotov6.ValidateDataResourceConfigResponse, error) {
+func (f *FunctionProvider) MoveResourceState(context.Context, *tfprotov6.MoveResourceStateRequest) (*tfprotov6.MoveResourceStateResponse, error) {
 	return nil, errors.New("not supported")
 }
-func (f *FunctionProvider) ReadDataSource(context.Context, *tfprotov6.ReadDataSourceRequest) (*tfprotov6.ReadDataSourceResponse, error) {
-	return nil, errors.New("not supported")
+func (f *FunctionProvider) ValidateEphemeralResourceConfig(ctx context.Context, req *tfprotov6.ValidateEphemeralResourceConfigRequest) (*tfprotov6.ValidateEphemeralResourceConfigResponse, error) {
+	if _, ok := f.EphemeralResources[req.TypeName]; !ok {
+		return nil, errors.New("unknown ephemeral resource " + req.TypeName)
+	}
+	// The configuration is type-checked against the schema by Tofu.
+	return &tfprotov6.ValidateEphemeralResourceConfigResponse{}, nil
+}
+func (f *FunctionProvider) OpenEphemeralResource(ctx context.Context, req *tfprotov6.OpenEphemeralResourceRequest) (*tfprotov6.OpenEphemeralResourceResponse, error) {
+	resource, ok := f.EphemeralResources[req.TypeName]
+	if !ok {
+		return nil, errors.New("unknown ephemeral resource " + req.TypeName)
+	}
+	call := beginCall(ctx, map[string]any{"ephemeral_resource": req.TypeName})
+	defer call.end()
+	result, private, renewAt, diags := resource.Open(req.Config)
+	return &tfprotov6.OpenEphemeralResourceResponse{
+		Result:      result,
+		Private:     private,
+		RenewAt:     renewAt,
+		Diagnostics: append(call.warnings, diags...),
+	}, nil
+}
+func (f *FunctionProvider) RenewEphemeralResource(ctx context.Context, req *tfprotov6.RenewEphemeralResourceRequest) (*tfprotov6.RenewEphemeralResourceResponse, error) {
+	resource, ok := f.EphemeralResources[req.TypeName]
+	if !ok {
+		return nil, errors.New("unknown ephemeral resource " + req.TypeName)
+	}
+	call := beginCall(ctx, map[string]any{"ephemeral_resource": req.TypeName})
+	defer call.end()
+	renewAt, diags := resource.Renew(req.Private)
+	return &tfprotov6.RenewEphemeralResourceResponse{
+		Private:     req.Private,
+		RenewAt:     renewAt,
+		Diagnostics: append(call.warnings, diags...),
+	}, nil
+}
+func (f *FunctionProvider) CloseEphemeralResource(ctx context.Context, req *tfprotov6.CloseEphemeralResourceRequest) (*tfprotov6.CloseEphemeralResourceResponse, error) {
+	resource, ok := f.EphemeralResources[req.TypeName]
+	if !ok {
+		return nil, errors.New("unknown ephemeral resource " + req.TypeName)
+	}
+	call := beginCall(ctx, map[string]any{"ephemeral_resource": req.TypeName})
+	defer call.end()
+	diags := resource.Close(req.Private)
+	return &tfprotov6.CloseEphemeralResourceResponse{
+		Diagnostics: append(call.warnings, diags...),
+	}, nil
+}
+func (f *FunctionProvider) ValidateDataResourceConfig(ctx context.Context, req *tfprotov6.ValidateDataResourceConfigRequest) (*tfprotov6.ValidateDataResourceConfigResponse, error) {
+	if _, ok := f.DataSources[req.TypeName]; !ok {
+		return nil, errors.New("unknown data source " + req.TypeName)
+	}
+	// The configuration is type-checked against the schema by Tofu.
+	return &tfprotov6.ValidateDataResourceConfigResponse{}, nil
+}
+func (f *FunctionProvider) ReadDataSource(ctx context.Context, req *tfprotov6.ReadDataSourceRequest) (*tfprotov6.ReadDataSourceResponse, error) {
+	dataSource, ok := f.DataSources[req.TypeName]
+	if !ok {
+		return nil, errors.New("unknown data source " + req.TypeName)
+	}
+	call := beginCall(ctx, map[string]any{"data_source": req.TypeName})
+	defer call.end()
+	state, diags := dataSource.Read(req.Config)
+	return &tfprotov6.ReadDataSourceResponse{
+		State:       state,
+		Diagnostics: append(call.warnings, diags...),
+	}, nil
 }
 func (f *FunctionProvider) CallFunction(ctx context.Context, req *tfprotov6.CallFunctionRequest) (*tfprotov6.CallFunctionResponse, error) {
+	defer beginCall(ctx, map[string]any{"function": req.Name}).end()
 	if fn, ok := f.StaticFunctions[req.Name]; ok {
 		ret, err := fn.Impl(req.Arguments)
 		return &tfprotov6.CallFunctionResponse{
@@ -121,99 +290,329 @@ func (f *FunctionProvider) GetFunctions(context.Context, *tfprotov6.GetFunctions
 	}, nil
 }
 
+// providerAddress is the registry address of the provider.
+const providerAddress = "registry.opentofu.org/opentofu/go"
+
+// providerTypeName is the name of the provider, which prefixes the names of its data sources and (ephemeral) resources.
+const providerTypeName = "go"
+
+// libraryEnv is the environment variable holding the path of a Go file loaded when the provider starts.
+// Its functions are available without configuring the provider, and its data sources and (ephemeral) resources are part of the provider schema,
+// which Tofu requests before configuring the provider.
+const libraryEnv = "TOFU_PROVIDER_GO_LIBRARY"
+
 func main() {
-	err := tf6server.Serve("registry.opentofu.org/opentofu/go", func() tfprotov6.ProviderServer {
-		provider := &FunctionProvider{
-			ProviderSchema: &tfprotov6.Schema{
-				Block: &tfprotov6.SchemaBlock{
-					Attributes: []*tfprotov6.SchemaAttribute{
-						&tfprotov6.SchemaAttribute{
-							Name:     "go",
-							Type:     tftypes.String,
-							Required: true,
-						},
-					},
-				},
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	library, err := loadStaticLibrary()
+	if err != nil {
+		panic(err)
+	}
+	err = serve(providerAddress, func() tfprotov6.ProviderServer {
+		provider, err := newProvider(library)
+		if err != nil {
+			panic(err)
+		}
+		return provider
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// loadStaticLibrary loads the Go file given by the environment, if any.
+func loadStaticLibrary() (*GoLibrary, error) {
+	path := os.Getenv(libraryEnv)
+	if path == "" {
+		return nil, nil
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	library, diags := LoadGo(string(source), ConvertOptions{})
+	if len(diags) > 0 {
+		return nil, fmt.Errorf("%s: %s: %s", path, diags[0].Summary, diags[0].Detail)
+	}
+	return library, nil
+}
+
+// newProvider returns the provider with all the backends, serving the static library if any.
+func newProvider(library *GoLibrary) (*FunctionProvider, error) {
+	provider := NewFunctionProvider(&YaegiBackend{}, &LuaBackend{}, &StarlarkBackend{}, &WasmBackend{})
+	if library != nil {
+		provider.StaticLibrary = library
+		if diags := mergeFunctions(provider.StaticFunctions, FunctionLibrary(library.Functions)); len(diags) > 0 {
+			return nil, fmt.Errorf("%s: %s: %s", os.Getenv(libraryEnv), diags[0].Summary, diags[0].Detail)
+		}
+		provider.DataSources = library.DataSources
+		provider.Resources = library.Resources
+		provider.EphemeralResources = library.EphemeralResources
+	}
+	return provider, nil
+}
+
+// NewFunctionProvider returns a provider loading functions using the given backends.
+// Each backend gets a provider attribute holding its source code.
+func NewFunctionProvider(backends ...Backend) *FunctionProvider {
+	var attributes []*tfprotov6.SchemaAttribute
+	for _, backend := range backends {
+		attributes = append(attributes, &tfprotov6.SchemaAttribute{
+			Name:        backend.Name(),
+			Type:        tftypes.String,
+			Optional:    true,
+			Description: backend.Description(),
+		})
+	}
+	attributes = append(attributes, &tfprotov6.SchemaAttribute{
+		Name:        "bytes_encoding",
+		Type:        tftypes.String,
+		Optional:    true,
+		Description: `How []byte values are represented as strings, either "base64" (the default) or "raw".`,
+	})
+	attributes = append(attributes, &tfprotov6.SchemaAttribute{
+		Name:        "settings",
+		Type:        tftypes.DynamicPseudoType,
+		Optional:    true,
+		Description: "Settings passed to the Init function of the Go code, available as the settings global in Lua and Starlark, and in the TOFU_SETTINGS environment variable of WebAssembly modules.",
+	})
+
+	return &FunctionProvider{
+		ProviderSchema: &tfprotov6.Schema{
+			Block: &tfprotov6.SchemaBlock{
+				Attributes: attributes,
 			},
-			Configure: func(config *tfprotov6.DynamicValue) (map[string]*Function, []*tfprotov6.Diagnostic) {
-				res, err := config.Unmarshal(tftypes.Map{ElementType: tftypes.String})
-				if err != nil {
-					return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
-						Severity: tfprotov6.DiagnosticSeverityError,
-						Summary:  "Invalid configure payload",
-						Detail:   err.Error(),
-					}}
-				}
-				cfg := make(map[string]tftypes.Value)
-				err = res.As(&cfg)
-				if err != nil {
-					return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
-						Severity: tfprotov6.DiagnosticSeverityError,
-						Summary:  "Invalid configure payload",
-						Detail:   err.Error(),
-					}}
-				}
+		},
+		StaticFunctions:    BuiltinFunctions(),
+		DataSources:        map[string]*DataSource{},
+		Resources:          map[string]*ManagedResource{},
+		EphemeralResources: map[string]*ManagedEphemeralResource{},
+		Backends:           backends,
+	}
+}
 
-				codeVal := cfg["go"]
-				var code string
-				err = codeVal.As(&code)
-				if err != nil {
-					return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
-						Severity: tfprotov6.DiagnosticSeverityError,
-						Summary:  "Invalid configure payload",
-						Detail:   err.Error(),
-					}}
-				}
+// configure loads the source code of the backends, and returns their functions along with the loaded libraries.
+func (f *FunctionProvider) configure(config *tfprotov6.DynamicValue) (functions map[string]*Function, libraries []Library, diags []*tfprotov6.Diagnostic) {
+	defer func() {
+		if hasErrors(diags) {
+			closeLibraries(libraries)
+		}
+	}()
 
-				interpreter := interp.New(interp.Options{})
-				if err := interpreter.Use(stdlib.Symbols); err != nil {
-					return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
-						Severity: tfprotov6.DiagnosticSeverityError,
-						Summary:  "Failed to load Go standard library",
-						Detail:   err.Error(),
-					}}
-				}
+	res, err := config.Unmarshal(f.ProviderSchema.ValueType())
+	if err != nil {
+		return nil, libraries, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid configure payload",
+			Detail:   err.Error(),
+		}}
+	}
+	cfg := make(map[string]tftypes.Value)
+	err = res.As(&cfg)
+	if err != nil {
+		return nil, libraries, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid configure payload",
+			Detail:   err.Error(),
+		}}
+	}
 
-				_, err = interpreter.Eval(code)
-				if err != nil {
-					return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
-						Severity: tfprotov6.DiagnosticSeverityError,
-						Summary:  "Failed to evaluate Go code",
-						Detail:   err.Error(),
-					}}
-				}
+	var opts ConvertOptions
+	if err := cfg["bytes_encoding"].As(&opts.BytesEncoding); err != nil {
+		return nil, libraries, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid configure payload",
+			Detail:   err.Error(),
+		}}
+	}
+	if err := opts.validate(); err != nil {
+		return nil, libraries, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity:  tfprotov6.DiagnosticSeverityError,
+			Summary:   "Invalid bytes_encoding",
+			Detail:    err.Error(),
+			Attribute: tftypes.NewAttributePath().WithAttributeName("bytes_encoding"),
+		}}
+	}
 
-				exports := interpreter.Symbols("lib")
-				libExports := exports["lib"]
+	settings, err := settingsValue(cfg["settings"])
+	if err != nil {
+		return nil, libraries, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid configure payload",
+			Detail:   err.Error(),
+		}}
+	}
+	if f.StaticLibrary != nil {
+		if diags := f.StaticLibrary.Init(settings); len(diags) > 0 {
+			return nil, libraries, diags
+		}
+	}
 
-				functions := map[string]*Function{}
-				for name, export := range libExports {
-					if export.Kind() != reflect.Func {
-						continue
-					}
-					fn, diags := GoFunctionToTFFunction(interpreter, export)
-					if len(diags) > 0 {
-						return nil, diags
-					}
-					functions[GoNameToTFName(name)] = fn
-				}
+	// The static functions are added to detect duplicates, and removed once all the libraries are loaded.
+	functions = map[string]*Function{}
+	for name, fn := range f.StaticFunctions {
+		functions[name] = fn
+	}
+	var loaded bool
+	for _, backend := range f.Backends {
+		var source string
+		if err := cfg[backend.Name()].As(&source); err != nil {
+			return nil, libraries, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+				Severity: tfprotov6.DiagnosticSeverityError,
+				Summary:  "Invalid configure payload",
+				Detail:   err.Error(),
+			}}
+		}
+		if source == "" {
+			continue
+		}
+		loaded = true
+		library, loadDiags := backend.Load(source, settings, opts)
+		if library != nil {
+			libraries = append(libraries, library)
+		}
+		for _, diag := range loadDiags {
+			if diag.Attribute == nil {
+				diag.Attribute = tftypes.NewAttributePath().WithAttributeName(backend.Name())
+			}
+		}
+		// Warnings don't prevent the library from being loaded.
+		diags = append(diags, loadDiags...)
+		if hasErrors(loadDiags) {
+			return nil, libraries, diags
+		}
+		if mergeDiags := mergeFunctions(functions, library); len(mergeDiags) > 0 {
+			return nil, libraries, append(diags, mergeDiags...)
+		}
+	}
+	for name := range f.StaticFunctions {
+		delete(functions, name)
+	}
+	if !loaded && f.StaticLibrary == nil {
+		var names []string
+		for _, backend := range f.Backends {
+			names = append(names, fmt.Sprintf("%q", backend.Name()))
+		}
+		return nil, libraries, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Missing source code",
+			Detail:   fmt.Sprintf("At least one of the %s attributes must be set.", strings.Join(names, ", ")),
+		}}
+	}
 
-				return functions, nil
-			},
-			StaticFunctions: map[string]*Function{},
+	return functions, libraries, diags
+}
+
+// hasErrors reports whether any of the diagnostics is an error.
+func hasErrors(diags []*tfprotov6.Diagnostic) bool {
+	for _, diag := range diags {
+		if diag.Severity == tfprotov6.DiagnosticSeverityError {
+			return true
 		}
-		return provider
-	})
+	}
+	return false
+}
+
+// validate checks the source code of the backends which support it, and the settings of the static library,
+// as far as they are known.
+func (f *FunctionProvider) validate(config *tfprotov6.DynamicValue) []*tfprotov6.Diagnostic {
+	res, err := config.Unmarshal(f.ProviderSchema.ValueType())
 	if err != nil {
-		panic(err)
+		return []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid provider configuration",
+			Detail:   err.Error(),
+		}}
 	}
+	cfg := make(map[string]tftypes.Value)
+	if err := res.As(&cfg); err != nil {
+		return []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid provider configuration",
+			Detail:   err.Error(),
+		}}
+	}
+
+	var diags []*tfprotov6.Diagnostic
+	if f.StaticLibrary != nil && cfg["settings"].IsFullyKnown() {
+		settings, err := settingsValue(cfg["settings"])
+		if err != nil {
+			return []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+				Severity: tfprotov6.DiagnosticSeverityError,
+				Summary:  "Invalid provider configuration",
+				Detail:   err.Error(),
+			}}
+		}
+		diags = append(diags, f.StaticLibrary.ValidateSettings(settings)...)
+	}
+	for _, backend := range f.Backends {
+		validator, ok := backend.(Validator)
+		value := cfg[backend.Name()]
+		if !ok || !value.IsKnown() || value.IsNull() {
+			continue
+		}
+		var source string
+		if err := value.As(&source); err != nil {
+			return []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+				Severity: tfprotov6.DiagnosticSeverityError,
+				Summary:  "Invalid provider configuration",
+				Detail:   err.Error(),
+			}}
+		}
+		for _, diag := range validator.Validate(source) {
+			if diag.Attribute == nil {
+				diag.Attribute = tftypes.NewAttributePath().WithAttributeName(backend.Name())
+			}
+			diags = append(diags, diag)
+		}
+	}
+	return diags
+}
+
+// settingsValue converts the value of the settings attribute to a cty value of its concrete type.
+func settingsValue(value tftypes.Value) (cty.Value, error) {
+	dv, err := tfprotov6.NewDynamicValue(tftypes.DynamicPseudoType, value)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ProtoToCty(cty.DynamicPseudoType, &dv)
+}
+
+// mergeFunctions adds the functions of the library to dst, making sure function names are unique.
+func mergeFunctions(dst map[string]*Function, library Library) []*tfprotov6.Diagnostic {
+	for name, signature := range library.Functions() {
+		if _, ok := dst[name]; ok {
+			return []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+				Severity: tfprotov6.DiagnosticSeverityError,
+				Summary:  "Duplicate function",
+				Detail:   fmt.Sprintf("The function %s is defined more than once.", name),
+			}}
+		}
+		name := name
+		dst[name] = &Function{
+			Function: *signature,
+			Impl: func(args []*tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, *tfprotov6.FunctionError) {
+				return library.Call(name, args)
+			},
+		}
+	}
+	return nil
 }
 
-func GoFunctionToTFFunction(interpreter *interp.Interpreter, fn reflect.Value) (*Function, []*tfprotov6.Diagnostic) {
+func GoFunctionToTFFunction(interpreter *interp.Interpreter, fn reflect.Value, opts ConvertOptions) (*Function, []*tfprotov6.Diagnostic) {
 	exportType := fn.Type()
 	var parameters []*tfprotov6.FunctionParameter
 	for i := 0; i < exportType.NumIn(); i++ {
-		functionParameter, err := GoTypeToTFFunctionParam(exportType.In(i))
+		functionParameter, err := GoTypeToTFFunctionParam(exportType.In(i), opts)
+		if err == nil {
+			err = checkText(exportType.In(i), textUnmarshalerType, opts)
+		}
 		if err != nil {
 			return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
 				Severity: tfprotov6.DiagnosticSeverityError,
@@ -242,7 +641,10 @@ func GoFunctionToTFFunction(interpreter *interp.Interpreter, fn reflect.Value) (
 		}}
 	}
 	output := exportType.Out(0)
-	outputType, err := GoTypeToTFType(output)
+	outputType, err := GoTypeToTFType(output, opts)
+	if err == nil {
+		err = checkText(output, textMarshalerType, opts)
+	}
 	if err != nil {
 		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
 			Severity: tfprotov6.DiagnosticSeverityError,
@@ -261,17 +663,31 @@ func GoFunctionToTFFunction(interpreter *interp.Interpreter, fn reflect.Value) (
 			goArgs := make([]reflect.Value, len(args))
 			for i, arg := range args {
 				var err error
-				goArg, err := ProtoToGo(parameters[i].Type, exportType.In(i), arg)
+				goArg, err := ProtoToGo(parameters[i].Type, exportType.In(i), arg, opts)
 				if err != nil {
+					argument := int64(i)
 					return nil, &tfprotov6.FunctionError{
-						Text: err.Error(),
+						Text:             err.Error(),
+						FunctionArgument: &argument,
 					}
 				}
 				goArgs[i] = reflect.ValueOf(goArg)
+				if !goArgs[i].IsValid() {
+					// A null passed to an interface{} parameter.
+					goArgs[i] = reflect.Zero(exportType.In(i))
+				}
 			}
 			goResult := fn.Call(goArgs)
 			if len(goResult) > 1 && !goResult[1].IsNil() {
 				err := goResult[1].Interface().(error)
+				var argErr *argumentError
+				if errors.As(err, &argErr) {
+					argument := int64(argErr.argument)
+					return nil, &tfprotov6.FunctionError{
+						Text:             argErr.err.Error(),
+						FunctionArgument: &argument,
+					}
+				}
 				if err != nil {
 					return nil, &tfprotov6.FunctionError{
 						Text: err.Error(),
@@ -279,7 +695,7 @@ func GoFunctionToTFFunction(interpreter *interp.Interpreter, fn reflect.Value) (
 				}
 			}
 
-			out, err := GoToProto(outputType, goResult[0].Interface())
+			out, err := GoToProto(outputType, goResult[0].Interface(), opts)
 			if err != nil {
 				return nil, &tfprotov6.FunctionError{
 					Text: err.Error(),
@@ -290,13 +706,8 @@ func GoFunctionToTFFunction(interpreter *interp.Interpreter, fn reflect.Value) (
 	}, nil
 }
 
-func TfValueToProto(tfType tftypes.Type, tfVal tftypes.Value) (*tfprotov6.DynamicValue, error) {
-	value, err := tfprotov6.NewDynamicValue(tfType, tfVal)
-	return &value, err
-}
-
-func GoTypeToTFFunctionParam(t reflect.Type) (*tfprotov6.FunctionParameter, error) {
-	outType, err := GoTypeToTFType(t)
+func GoTypeToTFFunctionParam(t reflect.Type, opts ConvertOptions) (*tfprotov6.FunctionParameter, error) {
+	outType, err := GoTypeToTFType(t, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -308,16 +719,32 @@ func GoTypeToTFFunctionParam(t reflect.Type) (*tfprotov6.FunctionParameter, erro
 	}, nil
 }
 
-func GoTypeToTFType(t reflect.Type) (tftypes.Type, error) {
+func GoTypeToTFType(t reflect.Type, opts ConvertOptions) (tftypes.Type, error) {
+	if conv, ok := opts.Types[t]; ok {
+		return conv.Type, nil
+	}
+
+	switch {
+	case isBytes(t), t == timeType, isText(t):
+		return tftypes.String, nil
+	case t == durationType:
+		if opts.DurationFormat == "seconds" {
+			return tftypes.Number, nil
+		}
+		return tftypes.String, nil
+	}
+
 	switch t.Kind() {
 	case reflect.String:
 		return tftypes.String, nil
 	case reflect.Bool:
 		return tftypes.Bool, nil
-	case reflect.Int, reflect.Float64:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
 		return tftypes.Number, nil
 	case reflect.Ptr:
-		return GoTypeToTFType(t.Elem())
+		return GoTypeToTFType(t.Elem(), opts)
 	case reflect.Interface:
 		if reflect.TypeFor[interface{}]().Implements(t) {
 			return tftypes.DynamicPseudoType, nil
@@ -325,7 +752,7 @@ func GoTypeToTFType(t reflect.Type) (tftypes.Type, error) {
 			return nil, fmt.Errorf("unsupported interface type %s, only interface{}/any interface type is supported", t.String())
 		}
 	case reflect.Slice:
-		elementType, err := GoTypeToTFType(t.Elem())
+		elementType, err := GoTypeToTFType(t.Elem(), opts)
 		if err != nil {
 			return nil, err
 		}
@@ -336,7 +763,7 @@ func GoTypeToTFType(t reflect.Type) (tftypes.Type, error) {
 		if t.Key().Kind() != reflect.String {
 			return nil, fmt.Errorf("unsupported map key type %s, only string keys are supported", t.Key().String())
 		}
-		valueType, err := GoTypeToTFType(t.Elem())
+		valueType, err := GoTypeToTFType(t.Elem(), opts)
 		if err != nil {
 			return nil, err
 		}
@@ -347,7 +774,11 @@ func GoTypeToTFType(t reflect.Type) (tftypes.Type, error) {
 		attributeTypes := make(map[string]tftypes.Type)
 		for i := 0; i < t.NumField(); i++ {
 			field := t.Field(i)
-			fieldType, err := GoTypeToTFType(field.Type)
+			fieldOpts, err := opts.withField(field)
+			if err != nil {
+				return nil, err
+			}
+			fieldType, err := GoTypeToTFType(field.Type, fieldOpts)
 			if err != nil {
 				return nil, err
 			}
@@ -362,292 +793,214 @@ func GoTypeToTFType(t reflect.Type) (tftypes.Type, error) {
 }
 
 func getTfObjectGoFieldName(field reflect.StructField) string {
-	if tag := field.Tag.Get("tf"); tag != "" {
-		return tag
+	if name, _, _ := strings.Cut(field.Tag.Get("tf"), ","); name != "" {
+		return name
 	}
 	return uncapitalize(field.Name)
 }
 
-func uncapitalize(s string) string {
-	if len(s) == 1 {
-		return strings.ToLower(s)
-	}
-	return strings.ToLower(s[:1]) + s[1:]
+// ConvertOptions tweaks how Go values are represented as Tofu values.
+// The provider configuration sets the defaults, which can be overridden per struct field
+// with options in the `tf` struct tag, e.g. `tf:"payload,raw"`.
+type ConvertOptions struct {
+	// BytesEncoding is the string encoding of []byte values, either "base64" (the default) or "raw".
+	BytesEncoding string
+	// DurationFormat is the representation of time.Duration values,
+	// either "string" (the default, e.g. "1h30m") or "seconds" (a number).
+	DurationFormat string
+	// Types holds the custom type conversions registered by the Go code.
+	Types TypeRegistry
 }
 
-func GoNameToTFName(name string) string {
-	return strings.ToLower(name)
+func (opts ConvertOptions) validate() error {
+	switch opts.BytesEncoding {
+	case "", "base64", "raw":
+		return nil
+	default:
+		return fmt.Errorf("unsupported bytes encoding %q, must be one of \"base64\" or \"raw\"", opts.BytesEncoding)
+	}
 }
 
-func ProtoToGo(argumentTfType tftypes.Type, argumentGoType reflect.Type, arg *tfprotov6.DynamicValue) (any, error) {
-	if len(arg.JSON) == 0 && len(arg.MsgPack) == 0 {
-		// This is an edge-case not properly handled by arg.IsNull().
-		// It happens when you pass (from tf) the value `null`, to a function expecting e.g. a string pointer.
-
-		// We can't just return nil here, because we need a *typed* interface{} :)
-		// If we'd return nil here, then the later reflect call of our dynamically created functions
-		// would fail during the dynamic type-check.
-		return reflect.Zero(argumentGoType).Interface(), nil
+// withField returns the options to use for the given struct field, based on its `tf` tag.
+func (opts ConvertOptions) withField(field reflect.StructField) (ConvertOptions, error) {
+	_, tagOpts, _ := strings.Cut(field.Tag.Get("tf"), ",")
+	if tagOpts == "" {
+		return opts, nil
 	}
-	argTf, err := arg.Unmarshal(argumentTfType)
-	if err != nil {
-		return nil, err
+	for _, opt := range strings.Split(tagOpts, ",") {
+		switch opt {
+		case "base64", "raw":
+			opts.BytesEncoding = opt
+		case "string", "seconds":
+			opts.DurationFormat = opt
+		case "computed":
+			// Only applies to schema attributes, see GoStructToSchemaAttributes.
+		case "volatile", "forcenew":
+			// Only apply to resource attributes, see NewManagedResource.
+		default:
+			return opts, fmt.Errorf("field %s: unsupported tf tag option %q", field.Name, opt)
+		}
 	}
+	return opts, nil
+}
 
-	return TfToGoValue(argumentGoType, argTf)
+func (opts ConvertOptions) encodeBytes(b []byte) (string, error) {
+	if opts.BytesEncoding == "raw" {
+		if !utf8.Valid(b) {
+			return "", errors.New("raw bytes are not valid UTF-8, use base64 encoding instead")
+		}
+		return string(b), nil
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
 }
 
-func TfToGoValue(goType reflect.Type, tfValue tftypes.Value) (any, error) {
-	if tfValue.IsNull() {
-		return nil, nil
+func (opts ConvertOptions) decodeBytes(s string) ([]byte, error) {
+	if opts.BytesEncoding == "raw" {
+		return []byte(s), nil
 	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 string: %w", err)
+	}
+	return b, nil
+}
 
-	switch goType.Kind() {
-	case reflect.String:
-		var str string
-		if err := tfValue.As(&str); err != nil {
-			return nil, err
-		}
-		return str, nil
-	case reflect.Bool:
-		var b bool
-		if err := tfValue.As(&b); err != nil {
-			return nil, err
-		}
-		return b, nil
-	case reflect.Int:
-		var bigFloat big.Float
-		if err := tfValue.As(&bigFloat); err != nil {
-			return nil, err
-		}
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	durationType        = reflect.TypeFor[time.Duration]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
 
-		f, _ := bigFloat.Int64()
-		return int(f), nil
-	case reflect.Float64:
-		var bigFloat big.Float
-		if err := tfValue.As(&bigFloat); err != nil {
-			return nil, err
-		}
+// isText reports whether t, or a pointer to it, implements encoding.TextMarshaler or encoding.TextUnmarshaler.
+// Values of such types are represented as strings, e.g. netip.Addr or netip.Prefix.
+// Pointers are handled by the pointer conversion instead, so they remain nullable.
+func isText(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+		return false
+	}
+	pt := reflect.PointerTo(t)
+	return t.Implements(textMarshalerType) || pt.Implements(textMarshalerType) || pt.Implements(textUnmarshalerType)
+}
 
-		f, _ := bigFloat.Float64()
-		return f, nil
-	case reflect.Ptr:
-		if tfValue.IsNull() {
-			return nil, nil
-		}
-		value, err := TfToGoValue(goType.Elem(), tfValue)
-		if err != nil {
-			return nil, err
-		}
-		// If we return &value, then the type will be *interface{}.
-		// So we construct a concrete type pointer via reflect.
-		// This way, we get e.g. *string instead of *interface{}.
-		out := reflect.New(reflect.TypeOf(value))
-		out.Elem().Set(reflect.ValueOf(value))
-		return out.Interface(), nil
-	case reflect.Interface:
-		panic("implement interface{}")
-	case reflect.Slice:
-		var tfValues []tftypes.Value
-		if err := tfValue.As(&tfValues); err != nil {
-			return nil, err
+// checkText returns an error if t is, or contains, a type for which isText is true but which doesn't implement iface:
+// encoding.TextUnmarshaler for values converted from Tofu values, and encoding.TextMarshaler for values converted to them.
+func checkText(t reflect.Type, iface reflect.Type, opts ConvertOptions) error {
+	if _, ok := opts.Types[t]; ok {
+		return nil
+	}
+	switch {
+	case isText(t):
+		// The method set of the pointer includes the methods with a value receiver.
+		if !reflect.PointerTo(t).Implements(iface) {
+			return fmt.Errorf("type %s doesn't implement %s", t, iface)
 		}
+		return nil
+	case isBytes(t), t == durationType:
+		return nil
+	}
 
-		out := reflect.MakeSlice(goType, len(tfValues), len(tfValues))
-		for i := 0; i < len(tfValues); i++ {
-			elem, err := TfToGoValue(goType.Elem(), tfValues[i])
-			if err != nil {
-				return nil, err
-			}
-			out.Index(i).Set(reflect.ValueOf(elem))
-		}
-		return out.Interface(), nil
-	case reflect.Map:
-		var tfMap map[string]tftypes.Value
-		if err := tfValue.As(&tfMap); err != nil {
-			return nil, err
-		}
-		out := reflect.MakeMap(goType)
-		for key, tfElement := range tfMap {
-			elem, err := TfToGoValue(goType.Elem(), tfElement)
-			if err != nil {
-				return nil, err
-			}
-			out.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(elem))
-		}
-		return out.Interface(), nil
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		return checkText(t.Elem(), iface, opts)
 	case reflect.Struct:
-		var tfMap map[string]tftypes.Value
-		if err := tfValue.As(&tfMap); err != nil {
-			return nil, err
-		}
-		// This is a fun one, you'd fine reflect.Zero(goType) should do the same, right?
-		// Nope! If you use reflect.Zero, then the fields of it won't be addressable.
-		// If the fields aren't addressable, they're not settable.
-		// So, we use reflect.New and then take the pointed-to value, this way it is in fact addressable.
-		out := reflect.New(goType).Elem()
-		for i := 0; i < goType.NumField(); i++ {
-			field := goType.Field(i)
-			tfName := getTfObjectGoFieldName(field)
-			tfElement, ok := tfMap[tfName]
-			if !ok {
-				return nil, fmt.Errorf("missing object field %s", tfName)
-			}
-			elem, err := TfToGoValue(field.Type, tfElement)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldOpts, err := opts.withField(field)
 			if err != nil {
-				return nil, err
+				return err
+			}
+			if err := checkText(field.Type, iface, fieldOpts); err != nil {
+				return fmt.Errorf("field %s: %w", field.Name, err)
 			}
-			out.Field(i).Set(reflect.ValueOf(elem))
 		}
-		return out.Interface(), nil
+	}
+	return nil
+}
 
-	default:
-		return nil, fmt.Errorf("unsupported type %s", goType.String())
-	}
-}
-
-// func CtyToGo(goType reflect.Type, ctyValue cty.Value) (any, error) {
-// 	ctyType := ctyValue.Type()
-// 	switch goType.Kind() {
-// 	case reflect.String:
-// 		if ctyType != cty.String {
-// 			return nil, fmt.Errorf("expected string, got %s", ctyType.FriendlyName())
-// 		}
-// 		return ctyValue.AsString(), nil
-// 	case reflect.Bool:
-// 		if ctyType != cty.Bool {
-// 			return nil, fmt.Errorf("expected bool, got %s", ctyType.FriendlyName())
-// 		}
-// 		return ctyValue.True(), nil
-// 	case reflect.Int:
-// 		if ctyType != cty.Number {
-// 			return nil, fmt.Errorf("expected number, got %s", ctyType.FriendlyName())
-// 		}
-// 		f, _ := ctyValue.AsBigFloat().Int64()
-// 		return int(f), nil
-// 	case reflect.Float64:
-// 		if ctyType != cty.Number {
-// 			return nil, fmt.Errorf("expected number, got %s", ctyType.FriendlyName())
-// 		}
-// 		f, _ := ctyValue.AsBigFloat().Float64()
-// 		return f, nil
-// 	case reflect.Ptr:
-// 		if ctyValue.IsNull() {
-// 			return nil, nil
-// 		}
-// 		return CtyToGo(goType.Elem(), ctyValue)
-// 	case reflect.Interface:
-// 		panic("implement interface{}")
-// 	case reflect.Slice:
-// 		if !ctyType.IsListType() {
-// 			return nil, fmt.Errorf("expected list, got %s", ctyType.FriendlyName())
-// 		}
-// 		out := reflect.MakeSlice(goType, ctyValue.LengthInt(), ctyValue.LengthInt())
-// 		for i := 0; i < ctyValue.LengthInt(); i++ {
-// 			elem, err := CtyToGo(goType.Elem(), ctyValue.Index(cty.NumberIntVal(int64(i))))
-// 			if err != nil {
-// 				return nil, err
-// 			}
-// 			out.Index(i).Set(reflect.ValueOf(elem))
-// 		}
-// 		return out.Interface(), nil
-// 	case reflect.Map:
-// 		if !ctyType.IsMapType() {
-// 			return nil, fmt.Errorf("expected map, got %s", ctyType.FriendlyName())
-// 		}
-// 		out := reflect.MakeMap(goType)
-// 		for key, ctyElement := range ctyValue.AsValueMap() {
-// 			elem, err := CtyToGo(goType.Elem(), ctyElement)
-// 			if err != nil {
-// 				return nil, err
-// 			}
-// 			out.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(elem))
-// 		}
-// 		return out.Interface(), nil
-// 	default:
-// 		return nil, fmt.Errorf("unsupported type %s", goType.String())
-// 	}
-// }
-
-func GoToProto(tfType tftypes.Type, value any) (*tfprotov6.DynamicValue, error) {
-	tfValue, err := GoToTfValue(tfType, value)
+// argumentError is the error converting an argument of a function,
+// returned by the function wrappers of the interpreter so that it's reported on the argument.
+type argumentError struct {
+	argument int
+	err      error
+}
+
+func (e *argumentError) Error() string {
+	return fmt.Sprintf("argument %d: %s", e.argument, e.err)
+}
+
+// marshalText converts a value of a type for which isText is true to a string.
+func marshalText(value reflect.Value) (string, error) {
+	if !value.Type().Implements(textMarshalerType) {
+		// The method might have a pointer receiver, so make the value addressable.
+		ptr := reflect.New(value.Type())
+		ptr.Elem().Set(value)
+		value = ptr
+	}
+	marshaler, ok := value.Interface().(encoding.TextMarshaler)
+	if !ok {
+		return "", fmt.Errorf("type %s does not implement encoding.TextMarshaler", value.Type().Elem())
+	}
+	text, err := marshaler.MarshalText()
 	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+// unmarshalText converts a string to a value of a type for which isText is true.
+func unmarshalText(t reflect.Type, str string) (any, error) {
+	out := reflect.New(t)
+	unmarshaler, ok := out.Interface().(encoding.TextUnmarshaler)
+	if !ok {
+		return nil, fmt.Errorf("type %s does not implement encoding.TextUnmarshaler", t)
+	}
+	if err := unmarshaler.UnmarshalText([]byte(str)); err != nil {
 		return nil, err
 	}
-	return TfValueToProto(tfType, tfValue)
+	return out.Elem().Interface(), nil
 }
 
-func GoToTfValue(tfType tftypes.Type, value any) (tftypes.Value, error) {
-	if value == nil {
-		if err := tftypes.ValidateValue(tfType, nil); err != nil {
-			return tftypes.Value{}, err
-		}
-		return tftypes.NewValue(tfType, nil), nil
+// isBytes reports whether t is []byte, or a named type based on it.
+func isBytes(t reflect.Type) bool {
+	return t != nil && t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+func uncapitalize(s string) string {
+	if len(s) == 1 {
+		return strings.ToLower(s)
 	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
 
-	switch {
-	case tfType.Is(tftypes.String):
-		return tftypes.NewValue(tftypes.String, value), nil
-	case tfType.Is(tftypes.Bool):
-		return tftypes.NewValue(tftypes.Bool, value), nil
-	case tfType.Is(tftypes.Number):
-		switch value := value.(type) {
-		case int:
-			return tftypes.NewValue(tftypes.Number, value), nil
-		case float64:
-			return tftypes.NewValue(tftypes.Number, value), nil
-		default:
-			return tftypes.Value{}, fmt.Errorf("expected number, got %T", value)
-		}
-	case tfType.Is(tftypes.DynamicPseudoType):
-		panic("implement interface{}")
-	default:
-		switch tfType := tfType.(type) {
-		case tftypes.List:
-			if reflect.TypeOf(value).Kind() != reflect.Slice {
-				return tftypes.Value{}, fmt.Errorf("expected slice, got %T", value)
-			}
-			slice := reflect.ValueOf(value)
-			out := make([]tftypes.Value, slice.Len())
-			for i := 0; i < slice.Len(); i++ {
-				elem, err := GoToTfValue(tfType.ElementType, slice.Index(i).Interface())
-				if err != nil {
-					return tftypes.Value{}, err
-				}
-				out[i] = elem
-			}
-			return tftypes.NewValue(tfType, out), nil
-		case tftypes.Map:
-			if reflect.TypeOf(value).Kind() != reflect.Map {
-				return tftypes.Value{}, fmt.Errorf("expected map, got %T", value)
-			}
-			m := reflect.ValueOf(value)
-			out := make(map[string]tftypes.Value, m.Len())
-			for _, key := range m.MapKeys() {
-				elem, err := GoToTfValue(tfType.ElementType, m.MapIndex(key).Interface())
-				if err != nil {
-					return tftypes.Value{}, err
-				}
-				out[key.String()] = elem
-			}
-			return tftypes.NewValue(tfType, out), nil
-		case tftypes.Object:
-			if reflect.TypeOf(value).Kind() != reflect.Struct {
-				return tftypes.Value{}, fmt.Errorf("expected struct, got %T", value)
-			}
-			out := make(map[string]tftypes.Value, len(tfType.AttributeTypes))
-			for i := 0; i < reflect.TypeOf(value).NumField(); i++ {
-				field := reflect.TypeOf(value).Field(i)
-				tfName := getTfObjectGoFieldName(field)
-				elem, err := GoToTfValue(tfType.AttributeTypes[tfName], reflect.ValueOf(value).Field(i).Interface())
-				if err != nil {
-					return tftypes.Value{}, err
-				}
-				out[tfName] = elem
-			}
-			return tftypes.NewValue(tfType, out), nil
-		default:
-			return tftypes.Value{}, fmt.Errorf("unsupported type %s", tfType.String())
-		}
+func GoNameToTFName(name string) string {
+	return strings.ToLower(name)
+}
+
+func ProtoToGo(argumentTfType tftypes.Type, argumentGoType reflect.Type, arg *tfprotov6.DynamicValue, opts ConvertOptions) (any, error) {
+	ctyType, err := TFTypeToCtyType(argumentTfType)
+	if err != nil {
+		return nil, err
+	}
+	argCty, err := ProtoToCty(ctyType, arg)
+	if err != nil {
+		return nil, formatCtyError(err)
+	}
+	goArg, err := CtyToGo(argumentGoType, argCty, opts)
+	if err != nil {
+		return nil, formatCtyError(err)
+	}
+	return goArg, nil
+}
+
+func GoToProto(tfType tftypes.Type, value any, opts ConvertOptions) (*tfprotov6.DynamicValue, error) {
+	ctyType, err := TFTypeToCtyType(tfType)
+	if err != nil {
+		return nil, err
+	}
+	ctyValue, err := GoToCty(ctyType, value, opts)
+	if err != nil {
+		return nil, formatCtyError(err)
 	}
+	return CtyToProto(ctyType, ctyValue)
 }
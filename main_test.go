@@ -0,0 +1,682 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestCallFunction(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]cty.Value
+		fn     string
+		args   []cty.Value
+		want   cty.Value
+	}{
+		{
+			name:   "go",
+			config: map[string]cty.Value{"go": cty.StringVal("package lib\nfunc Hello(name string) string { return \"Hello, \" + name + \"!\" }")},
+			fn:     "hello",
+			args:   []cty.Value{cty.StringVal("papaya")},
+			want:   cty.StringVal("Hello, papaya!"),
+		},
+		{
+			name: "go struct",
+			config: map[string]cty.Value{"go": cty.StringVal(`package lib
+type Point struct {
+	X, Y int
+	Label string ` + "`tf:\"name\"`" + `
+}
+func Move(p Point, by int) Point { p.X += by; p.Y += by; return p }`)},
+			fn: "move",
+			args: []cty.Value{
+				cty.ObjectVal(map[string]cty.Value{"x": cty.NumberIntVal(1), "y": cty.NumberIntVal(2), "name": cty.StringVal("a")}),
+				cty.NumberIntVal(3),
+			},
+			want: cty.ObjectVal(map[string]cty.Value{"x": cty.NumberIntVal(4), "y": cty.NumberIntVal(5), "name": cty.StringVal("a")}),
+		},
+		{
+			name:   "go nullable",
+			config: map[string]cty.Value{"go": cty.StringVal("package lib\nfunc Or(s *string, def string) string { if s == nil { return def }; return *s }")},
+			fn:     "or",
+			args:   []cty.Value{cty.NullVal(cty.String), cty.StringVal("default")},
+			want:   cty.StringVal("default"),
+		},
+		{
+			name:   "go dynamic",
+			config: map[string]cty.Value{"go": cty.StringVal("package lib\nfunc Wrap(v any) any { return map[string]any{\"value\": v, \"list\": []any{1, \"a\"}} }")},
+			fn:     "wrap",
+			args:   []cty.Value{cty.BoolVal(true)},
+			want: cty.ObjectVal(map[string]cty.Value{
+				"value": cty.True,
+				"list":  cty.TupleVal([]cty.Value{cty.NumberIntVal(1), cty.StringVal("a")}),
+			}),
+		},
+		{
+			name: "go text type",
+			config: map[string]cty.Value{"go": cty.StringVal(`package lib
+
+import (
+	"fmt"
+	"slices"
+)
+
+type Color int
+
+var colors = []string{"red", "green", "blue"}
+
+func (c Color) MarshalText() ([]byte, error) {
+	if int(c) >= len(colors) {
+		return nil, fmt.Errorf("invalid color %d", c)
+	}
+	return []byte(colors[c]), nil
+}
+
+func (c *Color) UnmarshalText(text []byte) error {
+	i := slices.Index(colors, string(text))
+	if i < 0 {
+		return fmt.Errorf("unknown color %q", text)
+	}
+	*c = Color(i)
+	return nil
+}
+
+func Next(c Color) Color { return (c + 1) % Color(len(colors)) }
+`)},
+			fn:   "next",
+			args: []cty.Value{cty.StringVal("blue")},
+			want: cty.StringVal("red"),
+		},
+		{
+			name: "go text type pointer",
+			config: map[string]cty.Value{"go": cty.StringVal(`package lib
+
+import (
+	"fmt"
+	"slices"
+)
+
+type Color int
+
+var colors = []string{"red", "green", "blue"}
+
+func (c Color) MarshalText() ([]byte, error) {
+	if int(c) >= len(colors) {
+		return nil, fmt.Errorf("invalid color %d", c)
+	}
+	return []byte(colors[c]), nil
+}
+
+func (c *Color) UnmarshalText(text []byte) error {
+	i := slices.Index(colors, string(text))
+	if i < 0 {
+		return fmt.Errorf("unknown color %q", text)
+	}
+	*c = Color(i)
+	return nil
+}
+
+func Or(c *Color, def Color) *Color { if c == nil { return &def }; return c }
+`)},
+			fn:   "or",
+			args: []cty.Value{cty.NullVal(cty.String), cty.StringVal("green")},
+			want: cty.StringVal("green"),
+		},
+		{
+			name: "go settings",
+			config: map[string]cty.Value{
+				"go": cty.StringVal(`package lib
+var prefix string
+func Init(settings struct{ Prefix string }) error { prefix = settings.Prefix; return nil }
+func Name(s string) string { return prefix + "-" + s }`),
+				"settings": cty.ObjectVal(map[string]cty.Value{"prefix": cty.StringVal("app")}),
+			},
+			fn:   "name",
+			args: []cty.Value{cty.StringVal("api")},
+			want: cty.StringVal("app-api"),
+		},
+		{
+			name:   "go raw bytes",
+			config: map[string]cty.Value{"go": cty.StringVal("package lib\nfunc Reverse(b []byte) []byte { for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 { b[i], b[j] = b[j], b[i] }; return b }"), "bytes_encoding": cty.StringVal("raw")},
+			fn:     "reverse",
+			args:   []cty.Value{cty.StringVal("abc")},
+			want:   cty.StringVal("cba"),
+		},
+		{
+			name:   "lua",
+			config: map[string]cty.Value{"lua": cty.StringVal("function add(a, b) return a + b end\nreturn { add = { params = { \"number\", \"number\" }, returns = \"number\" } }")},
+			fn:     "add",
+			args:   []cty.Value{cty.NumberIntVal(1), cty.NumberIntVal(2)},
+			want:   cty.NumberIntVal(3),
+		},
+		{
+			name:   "starlark",
+			config: map[string]cty.Value{"starlark": cty.StringVal("def upper(s):\n    return s.upper()\n\nsignature(upper, params = [\"string\"], returns = \"string\")\n")},
+			fn:     "upper",
+			args:   []cty.Value{cty.StringVal("papaya")},
+			want:   cty.StringVal("PAPAYA"),
+		},
+		{
+			name: "builtin",
+			fn:   "semver_compare",
+			args: []cty.Value{cty.StringVal("1.2.0"), cty.StringVal("v1.10.0")},
+			want: cty.NumberIntVal(-1),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The built-in functions are available without configuring the provider.
+			p := startProvider(t, "")
+			if test.config != nil {
+				requireNoErrors(t, p.configure(test.config))
+			}
+			got := p.mustCall(test.fn, test.args...)
+			if !got.Type().Equals(test.want.Type()) || !got.Equals(test.want).True() {
+				t.Fatalf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegisterType(t *testing.T) {
+	// The example of the README, with a Version which can't be converted.
+	p := configuredProvider(t, map[string]cty.Value{"go": cty.StringVal(`package lib
+
+import (
+	"errors"
+	"fmt"
+	"tofu"
+)
+
+type Version struct {
+	Major, Minor int
+}
+
+func init() {
+	tofu.RegisterType(Version{}, tofu.String,
+		func(v any) (any, error) {
+			version := v.(Version)
+			if version.Major < 0 {
+				return nil, errors.New("negative major version")
+			}
+			return fmt.Sprintf("%d.%d", version.Major, version.Minor), nil
+		},
+		func(v any) (any, error) {
+			var version Version
+			_, err := fmt.Sscanf(v.(string), "%d.%d", &version.Major, &version.Minor)
+			return version, err
+		},
+	)
+}
+
+func Bump(version Version) Version {
+	version.Minor++
+	return version
+}
+
+func Invalid() Version { return Version{Major: -1} }
+`)})
+
+	requireEqual(t, p.mustCall("bump", cty.StringVal("1.2")), cty.StringVal("1.3"))
+
+	_, funcErr := p.call("bump", cty.StringVal("latest"))
+	if funcErr == nil || !strings.Contains(funcErr.Text, "expected integer") {
+		t.Fatalf("got error %v, want the error of fromTF", funcErr)
+	}
+	if funcErr.FunctionArgument == nil || *funcErr.FunctionArgument != 0 {
+		t.Errorf("got error about argument %v, want argument 0", funcErr.FunctionArgument)
+	}
+	_, funcErr = p.call("invalid")
+	if funcErr == nil || !strings.Contains(funcErr.Text, "negative major version") {
+		t.Fatalf("got error %v, want the error of toTF", funcErr)
+	}
+}
+
+func TestCallFunctionErrors(t *testing.T) {
+	p := configuredProvider(t, map[string]cty.Value{"go": cty.StringVal(`package lib
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+func Fail(s string) (string, error) { return "", errors.New("failed on " + s) }
+
+type Color int
+
+func (c Color) MarshalText() ([]byte, error) {
+	if c != 0 {
+		return nil, fmt.Errorf("invalid color %d", c)
+	}
+	return []byte("red"), nil
+}
+
+func (c *Color) UnmarshalText(text []byte) error {
+	if string(text) != "red" {
+		return fmt.Errorf("unknown color %q", text)
+	}
+	return nil
+}
+
+func Paint(n int, c Color) Color { return c + Color(n) }
+
+func Small(n int8) int8 { return n }
+
+func Time(t time.Time) string { return t.String() }
+`)})
+
+	tests := []struct {
+		name     string
+		fn       string
+		args     []cty.Value
+		text     string
+		argument int64
+	}{
+		{name: "error", fn: "fail", args: []cty.Value{cty.StringVal("papaya")}, text: "failed on papaya", argument: -1},
+		{name: "out of range", fn: "small", args: []cty.Value{cty.NumberIntVal(300)}, text: "between -128 and 127", argument: 0},
+		{name: "invalid timestamp", fn: "time", args: []cty.Value{cty.StringVal("yesterday")}, text: "invalid RFC 3339 timestamp", argument: 0},
+		{name: "text argument", fn: "paint", args: []cty.Value{cty.NumberIntVal(0), cty.StringVal("blue")}, text: `unknown color "blue"`, argument: 1},
+		{name: "text result", fn: "paint", args: []cty.Value{cty.NumberIntVal(1), cty.StringVal("red")}, text: "invalid color 1", argument: -1},
+		{name: "null", fn: "fail", args: []cty.Value{cty.NullVal(cty.String)}, text: "failed on", argument: -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, funcErr := p.call(test.fn, test.args...)
+			if funcErr == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(funcErr.Text, test.text) {
+				t.Errorf("got error %q, want it to contain %q", funcErr.Text, test.text)
+			}
+			switch {
+			case test.argument < 0 && funcErr.FunctionArgument != nil:
+				t.Errorf("got error about argument %d, want a function error", *funcErr.FunctionArgument)
+			case test.argument >= 0 && (funcErr.FunctionArgument == nil || *funcErr.FunctionArgument != test.argument):
+				t.Errorf("got error about argument %v, want argument %d", funcErr.FunctionArgument, test.argument)
+			}
+		})
+	}
+}
+
+func TestConfigureDiagnostics(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]cty.Value
+		summary string
+		detail  string
+	}{
+		{
+			name:    "syntax error",
+			config:  map[string]cty.Value{"go": cty.StringVal("package lib\nfunc Hello( {")},
+			summary: "Invalid Go code",
+			detail:  "lib.go:2:13: expected ')'",
+		},
+		{
+			name:    "missing import",
+			config:  map[string]cty.Value{"go": cty.StringVal("package lib\nfunc Now() time.Time { return time.Now() }")},
+			summary: "Invalid Go code",
+		},
+		{
+			name:    "no result",
+			config:  map[string]cty.Value{"go": cty.StringVal("package lib\nfunc Hello() {}")},
+			summary: "Function must return a value",
+		},
+		{
+			name:    "unsupported type",
+			config:  map[string]cty.Value{"go": cty.StringVal("package lib\nfunc Hello(c chan int) string { return \"\" }")},
+			summary: "Failed to convert Argument type to TF type",
+			detail:  "unsupported type chan int",
+		},
+		{
+			name:    "builtin name",
+			config:  map[string]cty.Value{"go": cty.StringVal("package lib\nfunc Semver_compare(a, b string) int { return 0 }")},
+			summary: "Duplicate function",
+			detail:  "semver_compare",
+		},
+		{
+			name: "duplicate across backends",
+			config: map[string]cty.Value{
+				"go":  cty.StringVal("package lib\nfunc Hello() string { return \"\" }"),
+				"lua": cty.StringVal("function hello() return \"\" end\nreturn { hello = { params = {}, returns = \"string\" } }"),
+			},
+			summary: "Duplicate function",
+			detail:  "hello",
+		},
+		{
+			name:    "missing settings",
+			config:  map[string]cty.Value{"go": cty.StringVal("package lib\nfunc Init(settings struct{ Env string }) error { return nil }")},
+			summary: "Missing settings",
+		},
+		{
+			name:    "invalid bytes encoding",
+			config:  map[string]cty.Value{"bytes_encoding": cty.StringVal("hex")},
+			summary: "Invalid bytes_encoding",
+			detail:  "unsupported bytes encoding \"hex\"",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diags := startProvider(t, "").configure(test.config)
+			requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, test.summary, test.detail)
+		})
+	}
+}
+
+func TestNestedTextTypes(t *testing.T) {
+	// color declares the text type Color, after the imports of the tests.
+	const color = `
+type Color int
+
+func (c Color) MarshalText() ([]byte, error) { return []byte("red"), nil }
+
+func (c *Color) UnmarshalText(text []byte) error { *c = 0; return nil }
+`
+	tests := []struct {
+		name    string
+		imports string
+		code    string
+		detail  string
+	}{
+		{name: "parameters and results", code: "func Next(c Color, d *Color) (*Color, error) { return d, nil }"},
+		{name: "unexported type", code: "type palette []Color\nfunc Size() int { return len(palette{}) }"},
+		{name: "slice", code: "func First(c []Color) string { return \"\" }", detail: "First: the text type Color"},
+		{name: "map", code: "func Names() map[string]Color { return nil }", detail: "Names: the text type Color"},
+		{name: "pointer to pointer", code: "func Get(c **Color) int { return 0 }", detail: "Get: the text type Color"},
+		{
+			name:   "struct field",
+			code:   "type Shape struct {\n\tName  string\n\tColor Color\n}\nfunc Name(s Shape) string { return s.Name }",
+			detail: "Name: the text type Color",
+		},
+		{
+			name:   "declared slice",
+			code:   "type Palette []Color\ntype Theme struct{ Palette Palette }\nfunc Size(t Theme) int { return len(t.Palette) }",
+			detail: "Size: the text type Color",
+		},
+		{name: "init", code: "func Init(settings Color) error { return nil }", detail: "Init: the text type Color"},
+		{
+			name:   "data source",
+			code:   "type Input struct{ Color Color }\ntype Output struct{ Name string }\nfunc DataShape(in Input) (Output, error) { return Output{}, nil }",
+			detail: "DataShape: the text type Color",
+		},
+		{
+			name:    "resource",
+			imports: `import "tofu"`,
+			code: `
+type Shape struct{ Colors []Color }
+type shapes struct{}
+func (shapes) Create(plan any) (any, error)        { return plan, nil }
+func (shapes) Read(state any) (any, error)         { return state, nil }
+func (shapes) Update(prior, plan any) (any, error) { return plan, nil }
+func (shapes) Delete(state any) error              { return nil }
+func (shapes) Import(id string) (any, error)       { return nil, nil }
+func init() { tofu.RegisterResource("shape", Shape{}, shapes{}) }`,
+			detail: "RegisterResource: the text type Color",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, diags := LoadGo("package lib\n"+test.imports+color+test.code, ConvertOptions{})
+			if test.detail == "" {
+				requireNoDiagnostics(t, diags)
+				return
+			}
+			requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, "Invalid text type", test.detail)
+		})
+	}
+}
+
+func TestValidateGo(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		details []string
+	}{
+		{
+			name:    "syntax errors",
+			code:    "package lib\nfunc A( {\nfunc B() string { return \"\" \n",
+			details: []string{"lib.go:2:9: expected ')'", "lib.go:3:"},
+		},
+		{
+			name:    "type errors",
+			code:    "package lib\nfunc A() int { return \"a\" }\nfunc B() int { return \"b\" }",
+			details: []string{"lib.go:2:23: cannot use \"a\"", "lib.go:3:23: cannot use \"b\""},
+		},
+		{
+			name: "standard library",
+			code: `package lib
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+func A(s string) string { return strings.ToUpper(s, 1) }
+func B(d time.Duration) int { return d.Hours() }
+func C() fmt.Stringer { return strings.Builder{} }
+func D() fmt.Stringer { return &strings.Builder{} }
+func E() time.Time { return time.Now().Add(time.Second) }
+func F(s string) int { return strings.Count(s, time.Kitchen) }`,
+			details: []string{"lib.go:7:", "lib.go:8:", "lib.go:9:"},
+		},
+		{
+			name:    "generic packages",
+			code:    "package lib\nimport \"slices\"\nfunc A(s []string) bool { return slices.Contains(s, 1) }\nfunc B(s []string) int { return slices.Index(s, \"a\") }",
+			details: []string{"lib.go:3:"},
+		},
+		{
+			name: "tofu packages",
+			code: `package lib
+import (
+	"tofu"
+	"tofu/log"
+)
+func A() string { tofu.Warn("a"); log.Printf("%d", 1); return "" }
+func B() tofu.Type { return tofu.List(tofu.String) }`,
+			details: []string{"lib.go:6:"},
+		},
+		{
+			name:    "unknown package",
+			code:    "package lib\nimport \"os/exec\"\nfunc A() string { return exec.Command(\"ls\").Path }",
+			details: []string{"lib.go:2:8: could not import os/exec"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diags := startProvider(t, "").configure(map[string]cty.Value{"go": cty.StringVal(test.code)})
+			if len(diags) != len(test.details) {
+				t.Fatalf("got %d diagnostics, want %d: %v", len(diags), len(test.details), diags)
+			}
+			for _, detail := range test.details {
+				requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, "Invalid Go code", detail)
+			}
+		})
+	}
+}
+
+func TestValidateGoWarnings(t *testing.T) {
+	p := startProvider(t, "")
+	diags := p.configure(map[string]cty.Value{"go": cty.StringVal("package lib\nimport \"fmt\"\nfunc A() int { n := 1; return 2 }")})
+	if len(diags) != 2 {
+		t.Fatalf("got %d diagnostics, want 2: %v", len(diags), diags)
+	}
+	requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityWarning, "Invalid Go code", `lib.go:2:8: "fmt" imported and not used`)
+	requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityWarning, "Invalid Go code", "lib.go:3:16: declared and not used: n")
+	// The interpreter accepts the code anyway.
+	requireEqual(t, p.mustCall("a"), cty.NumberIntVal(2))
+}
+
+func TestSettings(t *testing.T) {
+	settings := cty.ObjectVal(map[string]cty.Value{"env": cty.StringVal("prod"), "replicas": cty.NumberIntVal(3)})
+	tests := []struct {
+		name   string
+		config map[string]cty.Value
+	}{
+		{
+			name: "go",
+			config: map[string]cty.Value{"go": cty.StringVal(`package lib
+import "fmt"
+type Settings struct {
+	Env      string
+	Replicas int
+}
+var settings Settings
+func Init(s Settings) error { settings = s; return nil }
+func Describe() string { return fmt.Sprintf("%s/%d", settings.Env, settings.Replicas) }`)},
+		},
+		{
+			name:   "lua",
+			config: map[string]cty.Value{"lua": cty.StringVal("function describe() return settings.env .. \"/\" .. math.floor(settings.replicas) end\nreturn { describe = { params = {}, returns = \"string\" } }")},
+		},
+		{
+			name:   "starlark",
+			config: map[string]cty.Value{"starlark": cty.StringVal("def describe():\n    return \"%s/%d\" % (settings[\"env\"], settings[\"replicas\"])\n\nsignature(describe, params = [], returns = \"string\")\n")},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.config["settings"] = settings
+			p := configuredProvider(t, test.config)
+			requireEqual(t, p.mustCall("describe"), cty.StringVal("prod/3"))
+		})
+	}
+}
+
+func TestSettingsDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		settings cty.Value
+		summary  string
+		detail   string
+	}{
+		{
+			name:     "invalid",
+			code:     "package lib\nfunc Init(settings struct{ Replicas int }) error { return nil }",
+			settings: cty.ObjectVal(map[string]cty.Value{"replicas": cty.StringVal("three")}),
+			summary:  "Invalid settings",
+			detail:   "replicas",
+		},
+		{
+			name:     "init error",
+			code:     "package lib\nimport \"errors\"\nfunc Init(settings string) error { return errors.New(\"unknown environment \" + settings) }",
+			settings: cty.StringVal("staging"),
+			summary:  "Failed to initialize Go code",
+			detail:   "unknown environment staging",
+		},
+		{
+			name:     "invalid Init",
+			code:     "package lib\nfunc Init(settings string) {}",
+			settings: cty.StringVal("staging"),
+			summary:  "Invalid Init function",
+			detail:   "func(settings T) error",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diags := startProvider(t, "").configure(map[string]cty.Value{"go": cty.StringVal(test.code), "settings": test.settings})
+			requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, test.summary, test.detail)
+		})
+	}
+}
+
+func TestConfigureIgnoredDataSources(t *testing.T) {
+	p := startProvider(t, "")
+	diags := p.configure(map[string]cty.Value{"go": cty.StringVal(`package lib
+
+import "tofu"
+
+type Input struct{ Name string }
+
+type Output struct{ Greeting string }
+
+func DataGreeting(in Input) (Output, error) { return Output{Greeting: "Hello, " + in.Name + "!"}, nil }
+
+type Bucket struct {
+	Name string
+}
+
+type buckets struct{}
+
+func (buckets) Create(plan any) (any, error)        { return plan, nil }
+func (buckets) Read(state any) (any, error)         { return state, nil }
+func (buckets) Update(prior, plan any) (any, error) { return plan, nil }
+func (buckets) Delete(state any) error              { return nil }
+func (buckets) Import(id string) (any, error)       { return Bucket{Name: id}, nil }
+
+func init() {
+	tofu.RegisterResource("bucket", Bucket{}, buckets{})
+}
+
+func Hello() string { return "hello" }
+`)})
+	requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityWarning, "Data sources and resources are ignored",
+		"declares the data source go_greeting, resource go_bucket, which are ignored")
+	requireEqual(t, p.mustCall("hello"), cty.StringVal("hello"))
+}
+
+func TestReadDataSource(t *testing.T) {
+	p := startProvider(t, `package lib
+
+import "tofu"
+
+type Input struct {
+	Name string
+}
+
+type Output struct {
+	Greeting string
+}
+
+func DataGreeting(in Input) (Output, error) {
+	if in.Name == "" {
+		tofu.Warn("Empty name", "Greeting nobody.")
+	}
+	return Output{Greeting: "Hello, " + in.Name + "!"}, nil
+}
+`)
+	requireNoErrors(t, p.configure(nil))
+
+	state, diags := p.readDataSource("go_greeting", map[string]cty.Value{"name": cty.StringVal("papaya")})
+	requireNoDiagnostics(t, diags)
+	requireEqual(t, state.GetAttr("greeting"), cty.StringVal("Hello, papaya!"))
+
+	_, diags = p.readDataSource("go_greeting", map[string]cty.Value{"name": cty.StringVal("")})
+	requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityWarning, "Empty name", "Greeting nobody.")
+}
+
+func TestValidateStaticSettings(t *testing.T) {
+	p := startProvider(t, `package lib
+var replicas int
+func Init(settings struct{ Replicas int }) error { replicas = settings.Replicas; return nil }
+func Replicas() int { return replicas }`)
+	tests := []struct {
+		name     string
+		settings cty.Value
+		summary  string
+	}{
+		{name: "valid", settings: cty.ObjectVal(map[string]cty.Value{"replicas": cty.NumberIntVal(3)})},
+		{name: "unknown", settings: cty.UnknownVal(cty.DynamicPseudoType)},
+		{name: "partially unknown", settings: cty.ObjectVal(map[string]cty.Value{"replicas": cty.UnknownVal(cty.Number)})},
+		{name: "missing", settings: cty.NullVal(cty.DynamicPseudoType), summary: "Missing settings"},
+		{name: "invalid", settings: cty.ObjectVal(map[string]cty.Value{"replicas": cty.StringVal("three")}), summary: "Invalid settings"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diags := p.validate(map[string]cty.Value{"settings": test.settings})
+			if test.summary == "" {
+				requireNoDiagnostics(t, diags)
+				return
+			}
+			requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, test.summary, "")
+			if want := tftypes.NewAttributePath().WithAttributeName("settings"); !diags[0].Attribute.Equal(want) {
+				t.Errorf("got attribute %v, want %v", diags[0].Attribute, want)
+			}
+		})
+	}
+	// Validating doesn't call Init.
+	requireEqual(t, p.mustCall("replicas"), cty.NumberIntVal(0))
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+const replHelp = `Enter an expression calling the functions, e.g. hello("papaya"), using HCL or JSON literals.
+Commands:
+  :functions         list the functions
+  :signature NAME    show the Tofu signature of a function
+  :reload            reload the source file, which is also reloaded when it changes
+  :quit              exit`
+
+// replCommand runs a read-eval-print loop calling the functions of a source file, e.g.:
+//
+//	terraform-provider-go repl lib.go
+func replCommand(args []string) error {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: terraform-provider-go repl [-settings JSON] FILE")
+		flags.PrintDefaults()
+	}
+	settings := flags.String("settings", "", "the settings of the provider, as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("missing file")
+	}
+
+	repl := &repl{path: flags.Arg(0), settings: *settings, out: os.Stdout}
+	if err := repl.load(); err != nil {
+		return err
+	}
+	fmt.Fprintln(repl.out, replHelp)
+	return repl.run(os.Stdin)
+}
+
+// repl holds the state of the read-eval-print loop.
+type repl struct {
+	path     string
+	settings string
+	out      io.Writer
+
+	provider  *FunctionProvider
+	functions map[string]*tfprotov6.Function
+	modTime   time.Time
+}
+
+// load configures the provider with the source file.
+func (r *repl) load() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	provider, err := configureFile(r.path, r.settings)
+	if err != nil {
+		return err
+	}
+	functions, err := provider.GetFunctions(context.Background(), &tfprotov6.GetFunctionsRequest{})
+	if err != nil {
+		return err
+	}
+	r.provider, r.functions, r.modTime = provider, functions.Functions, info.ModTime()
+	return nil
+}
+
+func (r *repl) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(r.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == ":quit" {
+			return nil
+		}
+		if err := r.eval(line); err != nil {
+			fmt.Fprintln(r.out, "Error:", err)
+		}
+	}
+}
+
+func (r *repl) eval(line string) error {
+	if info, err := os.Stat(r.path); err == nil && !info.ModTime().Equal(r.modTime) {
+		fmt.Fprintf(r.out, "Reloading %s\n", r.path)
+		if err := r.load(); err != nil {
+			// The file is reloaded again once fixed, until then the previous functions remain.
+			r.modTime = info.ModTime()
+			return err
+		}
+	}
+
+	command, arg, _ := strings.Cut(line, " ")
+	switch command {
+	case "":
+		return nil
+	case ":help":
+		fmt.Fprintln(r.out, replHelp)
+		return nil
+	case ":functions":
+		var names []string
+		for name := range r.functions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(r.out, strings.Join(names, "\n"))
+		return nil
+	case ":signature", ":sig":
+		fn, ok := r.functions[strings.TrimSpace(arg)]
+		if !ok {
+			return fmt.Errorf("unknown function %q", strings.TrimSpace(arg))
+		}
+		fmt.Fprintln(r.out, formatSignature(strings.TrimSpace(arg), fn))
+		if fn.Description != "" {
+			fmt.Fprintln(r.out, "\n"+fn.Description)
+		}
+		if fn.DeprecationMessage != "" {
+			fmt.Fprintln(r.out, "\nDeprecated: "+fn.DeprecationMessage)
+		}
+		return nil
+	case ":reload":
+		return r.load()
+	}
+	if strings.HasPrefix(command, ":") {
+		return fmt.Errorf("unknown command %s, see :help", command)
+	}
+
+	expr, diags := hclsyntax.ParseExpression([]byte(line), "repl", hcl.InitialPos)
+	if diags.HasErrors() {
+		return diags
+	}
+	functions := map[string]function.Function{}
+	for name, fn := range r.functions {
+		functions[name] = ctyFunction(r.provider, name, fn)
+	}
+	value, diags := expr.Value(&hcl.EvalContext{Functions: functions})
+	if diags.HasErrors() {
+		return diags
+	}
+	out, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return formatCtyError(err)
+	}
+	fmt.Fprintln(r.out, string(out))
+	return nil
+}
+
+// ctyFunction returns the function of the provider as a cty function, to call it from HCL expressions.
+func ctyFunction(provider *FunctionProvider, name string, fn *tfprotov6.Function) function.Function {
+	param := func(p *tfprotov6.FunctionParameter) *function.Parameter {
+		ctyType, err := TFTypeToCtyType(p.Type)
+		if err != nil {
+			ctyType = cty.DynamicPseudoType
+		}
+		return &function.Parameter{
+			Name:      p.Name,
+			Type:      ctyType,
+			AllowNull: p.AllowNullValue,
+		}
+	}
+	spec := &function.Spec{
+		Description: fn.Description,
+		Type: func(args []cty.Value) (cty.Type, error) {
+			return TFTypeToCtyType(fn.Return.Type)
+		},
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			return callFunction(provider, name, fn, args)
+		},
+	}
+	for _, p := range fn.Parameters {
+		spec.Params = append(spec.Params, *param(p))
+	}
+	if fn.VariadicParameter != nil {
+		spec.VarParam = param(fn.VariadicParameter)
+	}
+	return function.New(spec)
+}
+
+// formatSignature formats the signature of a function like Tofu type constraints, e.g. hello(name string) string.
+func formatSignature(name string, fn *tfprotov6.Function) string {
+	formatParam := func(p *tfprotov6.FunctionParameter) string {
+		s := formatType(p.Type)
+		if p.Name != "" {
+			s = p.Name + " " + s
+		}
+		if p.AllowNullValue {
+			s += " (nullable)"
+		}
+		return s
+	}
+
+	var params []string
+	for _, p := range fn.Parameters {
+		params = append(params, formatParam(p))
+	}
+	if fn.VariadicParameter != nil {
+		params = append(params, "..."+formatParam(fn.VariadicParameter))
+	}
+	return fmt.Sprintf("%s(%s) %s", name, strings.Join(params, ", "), formatType(fn.Return.Type))
+}
+
+// formatType formats a type like a Tofu type constraint, e.g. list(string).
+func formatType(t tftypes.Type) string {
+	ctyType, err := TFTypeToCtyType(t)
+	if err != nil {
+		return t.String()
+	}
+	return typeexpr.TypeString(ctyType)
+}
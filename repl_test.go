@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRepl(t *testing.T) {
+	t.Setenv(libraryEnv, "")
+	var out bytes.Buffer
+	r := &repl{path: writeSource(t, "lib.go", `package lib
+
+// Hello greets the given name.
+func Hello(name string) string { return "Hello, " + name + "!" }
+
+func Add(a, b int) int { return a + b }
+`), out: &out}
+	if err := r.load(); err != nil {
+		t.Fatal(err)
+	}
+	input := []string{
+		`hello("papaya")`,
+		`{ greeting = hello("papaya"), sum = add(1, 2) }`,
+		`:functions`,
+		`:signature hello`,
+		`:sig add`,
+		`:sig greet`,
+		`:greet`,
+		``,
+		`hello(`,
+		`hello([1])`,
+		`:quit`,
+		`hello("unreachable")`,
+	}
+	if err := r.run(strings.NewReader(strings.Join(input, "\n"))); err != nil {
+		t.Fatal(err)
+	}
+	want := `> "Hello, papaya!"
+> {"greeting":"Hello, papaya!","sum":3}
+> add
+cidr_contains
+hello
+regex_replace_all_named
+semver_compare
+template_render
+> hello(name string) string
+
+Hello greets the given name.
+> add(a number, b number) number
+> Error: unknown function "greet"
+> Error: unknown command :greet, see :help
+> > Error: repl:1,7-7: Missing expression; Expected the start of an expression, but found the end of the file.
+> Error: repl:1,7-8: Invalid function argument; Invalid value for "name" parameter: string required.
+> `
+	if got := out.String(); got != want {
+		t.Errorf("got output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestReplReload(t *testing.T) {
+	t.Setenv(libraryEnv, "")
+	var out bytes.Buffer
+	path := writeSource(t, "lib.go", "package lib\nfunc Hello(name string) string { return \"Hello, \" + name + \"!\" }")
+	r := &repl{path: path, out: &out}
+	if err := r.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	// write replaces the source file, with a later modification time than the loaded one.
+	write := func(source string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := r.modTime.Add(time.Second)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	eval := func(line, want string) {
+		t.Helper()
+		out.Reset()
+		err := r.eval(line)
+		if err != nil {
+			out.WriteString("Error: " + err.Error() + "\n")
+		}
+		if got := out.String(); !strings.Contains(got, want) {
+			t.Errorf("%s: got output:\n%s\nwant it to contain:\n%s", line, got, want)
+		}
+	}
+
+	write("package lib\nfunc Hello(name string) string { return \"Hi, \" + name + \"!\" }")
+	eval(`hello("papaya")`, "Reloading "+path+"\n\"Hi, papaya!\"\n")
+
+	// Invalid code keeps the previous functions, and isn't reloaded again until it changes.
+	write("package lib\nfunc Hello(name string) string {")
+	eval(`hello("papaya")`, "Error: failed to load "+path)
+	eval(`hello("papaya")`, "\"Hi, papaya!\"\n")
+
+	write("package lib\nfunc Greet(name string) string { return \"Hello, \" + name + \"!\" }")
+	eval(`greet("papaya")`, "\"Hello, papaya!\"\n")
+	eval(`hello("papaya")`, `Call to unknown function; There is no function named "hello".`)
+}
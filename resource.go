@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ManagedResource is a managed resource implemented by Go code.
+type ManagedResource struct {
+	Schema   *tfprotov6.Schema
+	resource Resource
+	goType   reflect.Type
+	ctyType  cty.Type
+	opts     ConvertOptions
+	// volatile are the computed attributes which change on every update, and forceNew
+	// the configurable attributes whose changes require replacing the resource, by name.
+	volatile, forceNew map[string]bool
+}
+
+// NewManagedResource returns the managed resource of a resource registered by the Go code.
+// The fields of the state struct become the attributes of the resource: computed if they're tagged with the
+// computed option, like `tf:"id,computed"`, otherwise configurable and required unless they are pointers.
+// Computed fields with the volatile option change on every update, and changes of configurable fields
+// with the forcenew option replace the resource.
+func NewManagedResource(registered *RegisteredResource, opts ConvertOptions) (*ManagedResource, error) {
+	attributes, err := GoStructToSchemaAttributes(registered.StateType, opts, false)
+	if err != nil {
+		return nil, err
+	}
+	volatile, forceNew := map[string]bool{}, map[string]bool{}
+	for i := 0; i < registered.StateType.NumField(); i++ {
+		field := registered.StateType.Field(i)
+		_, tagOpts, _ := strings.Cut(field.Tag.Get("tf"), ",")
+		for _, opt := range strings.Split(tagOpts, ",") {
+			switch {
+			case opt == "volatile" && !attributes[i].Computed:
+				return nil, fmt.Errorf("field %s: the volatile option only applies to computed fields", field.Name)
+			case opt == "volatile":
+				volatile[attributes[i].Name] = true
+			case opt == "forcenew" && attributes[i].Computed:
+				return nil, fmt.Errorf("field %s: the forcenew option only applies to configurable fields", field.Name)
+			case opt == "forcenew":
+				forceNew[attributes[i].Name] = true
+			}
+		}
+	}
+	attributes, err = mergeSchemaAttributes(attributes)
+	if err != nil {
+		return nil, err
+	}
+	// Values are converted both ways, e.g. from the plan and to the new state.
+	for _, iface := range []reflect.Type{textUnmarshalerType, textMarshalerType} {
+		if err := checkText(registered.StateType, iface, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	schema := &tfprotov6.Schema{
+		Block: &tfprotov6.SchemaBlock{
+			Attributes: attributes,
+		},
+	}
+	ctyType, err := TFTypeToCtyType(schema.ValueType())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ManagedResource{
+		Schema:   schema,
+		resource: registered.Resource,
+		goType:   registered.StateType,
+		ctyType:  ctyType,
+		opts:     opts,
+		volatile: volatile,
+		forceNew: forceNew,
+	}, nil
+}
+
+// Upgrade decodes the stored state. Resources have a single schema version, so there's nothing to upgrade,
+// but attributes which were removed from the state struct are dropped.
+func (r *ManagedResource) Upgrade(rawState *tfprotov6.RawState) (*tfprotov6.DynamicValue, []*tfprotov6.Diagnostic) {
+	value, err := rawState.UnmarshalWithOpts(r.Schema.ValueType(), tfprotov6.UnmarshalOpts{
+		ValueFromJSONOpts: tftypes.ValueFromJSONOpts{IgnoreUndefinedAttributes: true},
+	})
+	if err != nil {
+		return nil, resourceDiagnostics("Invalid resource state", err)
+	}
+	state, err := tfprotov6.NewDynamicValue(r.Schema.ValueType(), value)
+	if err != nil {
+		return nil, resourceDiagnostics("Invalid resource state", err)
+	}
+	return &state, nil
+}
+
+// Read refreshes the state of the resource, which is null if the resource doesn't exist anymore.
+func (r *ManagedResource) Read(current *tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, []*tfprotov6.Diagnostic) {
+	state, err := r.toGo(current)
+	if err != nil {
+		return nil, resourceDiagnostics("Invalid resource state", err)
+	}
+	newState, err := r.resource.Read(state)
+	if err != nil {
+		return nil, resourceDiagnostics("Failed to read resource", err)
+	}
+	return r.fromGo(newState, "Invalid resource state")
+}
+
+// Plan plans the change of the resource, and returns the attributes whose changes require replacing it.
+// Computed attributes are unknown when the resource is created or replaced, and otherwise keep their prior values,
+// which Tofu copies to the proposed state, except volatile ones which are unknown whenever a configurable attribute changes.
+func (r *ManagedResource) Plan(prior, proposed *tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, []*tftypes.AttributePath, []*tfprotov6.Diagnostic) {
+	proposedValue, err := ProtoToCty(r.ctyType, proposed)
+	if err != nil {
+		return nil, nil, resourceDiagnostics("Invalid planned state", formatCtyError(err))
+	}
+	if proposedValue.IsNull() {
+		// The resource is being destroyed.
+		return proposed, nil, nil
+	}
+	priorValue, err := ProtoToCty(r.ctyType, prior)
+	if err != nil {
+		return nil, nil, resourceDiagnostics("Invalid prior state", formatCtyError(err))
+	}
+
+	create := priorValue.IsNull()
+	changed := create
+	var requiresReplace []*tftypes.AttributePath
+	attributes := proposedValue.AsValueMap()
+	// The attributes are sorted by name, so the paths are too.
+	for _, attribute := range r.Schema.Block.Attributes {
+		if attribute.Computed || create || attributes[attribute.Name].RawEquals(priorValue.GetAttr(attribute.Name)) {
+			continue
+		}
+		changed = true
+		if r.forceNew[attribute.Name] {
+			requiresReplace = append(requiresReplace, tftypes.NewAttributePath().WithAttributeName(attribute.Name))
+		}
+	}
+	for _, attribute := range r.Schema.Block.Attributes {
+		if attribute.Computed && (create || len(requiresReplace) > 0 || changed && r.volatile[attribute.Name]) {
+			attributes[attribute.Name] = cty.UnknownVal(r.ctyType.AttributeType(attribute.Name))
+		}
+	}
+
+	planned, err := CtyToProto(r.ctyType, cty.ObjectVal(attributes))
+	if err != nil {
+		return nil, nil, resourceDiagnostics("Invalid planned state", err)
+	}
+	return planned, requiresReplace, nil
+}
+
+// Apply creates, updates or deletes the resource, and returns its new state.
+func (r *ManagedResource) Apply(prior, planned *tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, []*tfprotov6.Diagnostic) {
+	priorState, err := r.toGo(prior)
+	if err != nil {
+		return nil, resourceDiagnostics("Invalid prior state", err)
+	}
+	plannedState, err := r.toGo(planned)
+	if err != nil {
+		return nil, resourceDiagnostics("Invalid planned state", err)
+	}
+
+	var newState any
+	switch {
+	case plannedState == nil:
+		if err := r.resource.Delete(priorState); err != nil {
+			return nil, resourceDiagnostics("Failed to delete resource", err)
+		}
+	case priorState == nil:
+		newState, err = r.resource.Create(plannedState)
+		if err != nil {
+			return nil, resourceDiagnostics("Failed to create resource", err)
+		}
+	default:
+		newState, err = r.resource.Update(priorState, plannedState)
+		if err != nil {
+			return nil, resourceDiagnostics("Failed to update resource", err)
+		}
+	}
+	return r.fromGo(newState, "Invalid resource state")
+}
+
+// Import returns the state of the existing resource with the given ID.
+func (r *ManagedResource) Import(id string) (*tfprotov6.DynamicValue, []*tfprotov6.Diagnostic) {
+	state, err := r.resource.Import(id)
+	if err != nil {
+		return nil, resourceDiagnostics("Failed to import resource", err)
+	}
+	if state == nil {
+		return nil, resourceDiagnostics("Failed to import resource", fmt.Errorf("resource %s doesn't exist", id))
+	}
+	return r.fromGo(state, "Invalid resource state")
+}
+
+// toGo converts a state to the state struct, or nil if the state is null.
+// Unknown values, which are the computed attributes of planned states, become zero values.
+func (r *ManagedResource) toGo(state *tfprotov6.DynamicValue) (any, error) {
+	value, err := ProtoToCty(r.ctyType, state)
+	if err != nil {
+		return nil, formatCtyError(err)
+	}
+	if value.IsNull() {
+		return nil, nil
+	}
+	goValue, err := CtyToGo(r.goType, cty.UnknownAsNull(value), r.opts)
+	if err != nil {
+		return nil, formatCtyError(err)
+	}
+	return goValue, nil
+}
+
+// fromGo converts the state struct returned by the Go code to a state, which is null if the Go value is nil.
+func (r *ManagedResource) fromGo(state any, summary string) (*tfprotov6.DynamicValue, []*tfprotov6.Diagnostic) {
+	value := cty.NullVal(r.ctyType)
+	if state != nil {
+		if t := reflect.TypeOf(state); t != r.goType {
+			return nil, resourceDiagnostics(summary, fmt.Errorf("expected a state of type %s, got %s", r.goType, t))
+		}
+		var err error
+		value, err = GoToCty(r.ctyType, state, r.opts)
+		if err != nil {
+			return nil, resourceDiagnostics(summary, formatCtyError(err))
+		}
+	}
+	out, err := CtyToProto(r.ctyType, value)
+	if err != nil {
+		return nil, resourceDiagnostics(summary, err)
+	}
+	return out, nil
+}
+
+func resourceDiagnostics(summary string, err error) []*tfprotov6.Diagnostic {
+	return []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+		Severity: tfprotov6.DiagnosticSeverityError,
+		Summary:  summary,
+		Detail:   err.Error(),
+	}}
+}
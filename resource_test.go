@@ -0,0 +1,259 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// testResourceLibrary manages files in memory, whose ID must be kept across updates.
+const testResourceLibrary = `package lib
+
+import (
+	"errors"
+	"strings"
+	"tofu"
+)
+
+type File struct {
+	Path    string ` + "`tf:\"path,forcenew\"`" + `
+	Content string
+	Mode    *string
+	ID      string ` + "`tf:\"id,computed\"`" + `
+	Version int    ` + "`tf:\"version,computed,volatile\"`" + `
+}
+
+var files = map[string]File{}
+
+type fileResource struct{}
+
+func (fileResource) Create(plan any) (any, error) {
+	file := plan.(File)
+	file.ID = "file-" + file.Path
+	file.Version = 1
+	files[file.ID] = file
+	return file, nil
+}
+
+func (fileResource) Read(state any) (any, error) {
+	file, ok := files[state.(File).ID]
+	if !ok {
+		return nil, nil
+	}
+	return file, nil
+}
+
+func (fileResource) Update(prior, plan any) (any, error) {
+	file := plan.(File)
+	if file.ID != prior.(File).ID {
+		return nil, errors.New("the ID changed from " + prior.(File).ID + " to " + file.ID)
+	}
+	file.Version = prior.(File).Version + 1
+	files[file.ID] = file
+	return file, nil
+}
+
+func (fileResource) Delete(state any) error {
+	delete(files, state.(File).ID)
+	return nil
+}
+
+func (fileResource) Import(id string) (any, error) {
+	path, ok := strings.CutPrefix(id, "file-")
+	if !ok {
+		return nil, errors.New("invalid ID " + id)
+	}
+	return File{Path: path, Content: "imported", ID: id, Version: 1}, nil
+}
+
+func init() {
+	tofu.RegisterResource("file", File{}, fileResource{})
+}
+`
+
+// testFile returns the state of a file of testResourceLibrary, without mode.
+func testFile(path, content string, id, version cty.Value) cty.Value {
+	return cty.ObjectVal(map[string]cty.Value{
+		"path":    cty.StringVal(path),
+		"content": cty.StringVal(content),
+		"mode":    cty.NullVal(cty.String),
+		"id":      id,
+		"version": version,
+	})
+}
+
+func TestResourceLifecycle(t *testing.T) {
+	p := startProvider(t, testResourceLibrary)
+	unknownID, unknownVersion := cty.UnknownVal(cty.String), cty.UnknownVal(cty.Number)
+
+	// The steps run in order, from the state of the previous step.
+	steps := []struct {
+		name    string
+		config  map[string]cty.Value
+		plan    cty.Value
+		replace []string
+		state   cty.Value
+	}{
+		{
+			name:   "create",
+			config: map[string]cty.Value{"path": cty.StringVal("a"), "content": cty.StringVal("one")},
+			plan:   testFile("a", "one", unknownID, unknownVersion),
+			state:  testFile("a", "one", cty.StringVal("file-a"), cty.NumberIntVal(1)),
+		},
+		{
+			name:   "update",
+			config: map[string]cty.Value{"path": cty.StringVal("a"), "content": cty.StringVal("two")},
+			plan:   testFile("a", "two", cty.StringVal("file-a"), unknownVersion),
+			state:  testFile("a", "two", cty.StringVal("file-a"), cty.NumberIntVal(2)),
+		},
+		{
+			name:   "no change",
+			config: map[string]cty.Value{"path": cty.StringVal("a"), "content": cty.StringVal("two")},
+			plan:   testFile("a", "two", cty.StringVal("file-a"), cty.NumberIntVal(2)),
+			state:  testFile("a", "two", cty.StringVal("file-a"), cty.NumberIntVal(3)),
+		},
+		{
+			name:    "replace",
+			config:  map[string]cty.Value{"path": cty.StringVal("b"), "content": cty.StringVal("two")},
+			plan:    testFile("b", "two", unknownID, unknownVersion),
+			replace: []string{"path"},
+			state:   testFile("b", "two", cty.StringVal("file-b"), cty.NumberIntVal(1)),
+		},
+		{
+			name:  "delete",
+			plan:  cty.NullVal(p.resourceType("go_file")),
+			state: cty.NilVal,
+		},
+	}
+	state := cty.NilVal
+	for _, step := range steps {
+		planned, requiresReplace, diags := p.planResource("go_file", state, step.config)
+		requireNoErrors(t, diags)
+		requireEqual(t, planned, step.plan)
+		if len(requiresReplace) != len(step.replace) {
+			t.Fatalf("%s: got replaced attributes %v, want %v", step.name, requiresReplace, step.replace)
+		}
+		for i, name := range step.replace {
+			if !requiresReplace[i].Equal(tftypes.NewAttributePath().WithAttributeName(name)) {
+				t.Fatalf("%s: got replaced attributes %v, want %v", step.name, requiresReplace, step.replace)
+			}
+		}
+
+		if len(requiresReplace) > 0 {
+			// Like Tofu, the resource is deleted, and created again.
+			_, diags = p.applyResource("go_file", state, cty.NullVal(p.resourceType("go_file")))
+			requireNoErrors(t, diags)
+			state = cty.NilVal
+			planned, _, diags = p.planResource("go_file", state, step.config)
+			requireNoErrors(t, diags)
+		}
+		// The no change step is applied anyway, as an update without changes.
+		state, diags = p.applyResource("go_file", state, planned)
+		requireNoErrors(t, diags)
+		if step.state == cty.NilVal {
+			if !state.IsNull() {
+				t.Fatalf("%s: got state %#v, want it deleted", step.name, state)
+			}
+			continue
+		}
+		requireEqual(t, state, step.state)
+	}
+}
+
+func TestImportResource(t *testing.T) {
+	p := startProvider(t, testResourceLibrary)
+	tests := []struct {
+		id     string
+		state  cty.Value
+		detail string
+	}{
+		{id: "file-a", state: testFile("a", "imported", cty.StringVal("file-a"), cty.NumberIntVal(1))},
+		{id: "a", detail: "invalid ID a"},
+	}
+	for _, test := range tests {
+		t.Run(test.id, func(t *testing.T) {
+			state, diags := p.importResource("go_file", test.id)
+			if test.detail != "" {
+				requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, "Failed to import resource", test.detail)
+				return
+			}
+			requireNoErrors(t, diags)
+			requireEqual(t, state, test.state)
+		})
+	}
+}
+
+func TestUpgradeResourceState(t *testing.T) {
+	p := startProvider(t, testResourceLibrary)
+	tests := []struct {
+		name   string
+		state  string
+		want   cty.Value
+		detail string
+	}{
+		{
+			name:  "current",
+			state: `{"path": "a", "content": "one", "mode": null, "id": "file-a", "version": 1}`,
+			want:  testFile("a", "one", cty.StringVal("file-a"), cty.NumberIntVal(1)),
+		},
+		{
+			name:  "removed attribute",
+			state: `{"path": "a", "content": "one", "mode": null, "id": "file-a", "version": 1, "owner": "papaya"}`,
+			want:  testFile("a", "one", cty.StringVal("file-a"), cty.NumberIntVal(1)),
+		},
+		{
+			name:   "invalid",
+			state:  `{"path": "a", "content": "one", "mode": null, "id": "file-a", "version": "one"}`,
+			detail: "version",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state, diags := p.upgradeResource("go_file", test.state)
+			if test.detail != "" {
+				requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, "Invalid resource state", test.detail)
+				return
+			}
+			requireNoErrors(t, diags)
+			requireEqual(t, state, test.want)
+		})
+	}
+}
+
+func TestResourceTagOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		field  string
+		detail string
+	}{
+		{name: "volatile", field: "Version int `tf:\"version,volatile\"`", detail: "field Version: the volatile option only applies to computed fields"},
+		{name: "forcenew", field: "ID string `tf:\"id,computed,forcenew\"`", detail: "field ID: the forcenew option only applies to configurable fields"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, diags := LoadGo(`package lib
+
+import "tofu"
+
+type State struct {
+	`+test.field+`
+}
+
+type resource struct{}
+
+func (resource) Create(plan any) (any, error)        { return plan, nil }
+func (resource) Read(state any) (any, error)         { return state, nil }
+func (resource) Update(prior, plan any) (any, error) { return plan, nil }
+func (resource) Delete(state any) error              { return nil }
+func (resource) Import(id string) (any, error)       { return nil, nil }
+
+func init() {
+	tofu.RegisterResource("example", State{}, resource{})
+}
+`, ConvertOptions{})
+			requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, "Invalid resource", test.detail)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// The JSON representation of provider schemas, as printed by tofu providers schema -json.
+type (
+	jsonProviderSchemas struct {
+		FormatVersion   string                         `json:"format_version"`
+		ProviderSchemas map[string]*jsonProviderSchema `json:"provider_schemas"`
+	}
+	jsonProviderSchema struct {
+		Provider                 *jsonSchema              `json:"provider,omitempty"`
+		ResourceSchemas          map[string]*jsonSchema   `json:"resource_schemas,omitempty"`
+		DataSourceSchemas        map[string]*jsonSchema   `json:"data_source_schemas,omitempty"`
+		EphemeralResourceSchemas map[string]*jsonSchema   `json:"ephemeral_resource_schemas,omitempty"`
+		Functions                map[string]*jsonFunction `json:"functions,omitempty"`
+	}
+	jsonSchema struct {
+		Version int64      `json:"version"`
+		Block   *jsonBlock `json:"block,omitempty"`
+	}
+	jsonBlock struct {
+		Attributes      map[string]*jsonAttribute `json:"attributes,omitempty"`
+		Description     string                    `json:"description,omitempty"`
+		DescriptionKind string                    `json:"description_kind,omitempty"`
+		Deprecated      bool                      `json:"deprecated,omitempty"`
+	}
+	jsonAttribute struct {
+		Type            json.RawMessage `json:"type,omitempty"`
+		Description     string          `json:"description,omitempty"`
+		DescriptionKind string          `json:"description_kind,omitempty"`
+		Deprecated      bool            `json:"deprecated,omitempty"`
+		Required        bool            `json:"required,omitempty"`
+		Optional        bool            `json:"optional,omitempty"`
+		Computed        bool            `json:"computed,omitempty"`
+		Sensitive       bool            `json:"sensitive,omitempty"`
+	}
+	jsonFunction struct {
+		Description        string               `json:"description,omitempty"`
+		Summary            string               `json:"summary,omitempty"`
+		DeprecationMessage string               `json:"deprecation_message,omitempty"`
+		ReturnType         json.RawMessage      `json:"return_type"`
+		Parameters         []*jsonFunctionParam `json:"parameters,omitempty"`
+		VariadicParameter  *jsonFunctionParam   `json:"variadic_parameter,omitempty"`
+	}
+	jsonFunctionParam struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		IsNullable  bool            `json:"is_nullable,omitempty"`
+		Type        json.RawMessage `json:"type"`
+	}
+)
+
+// schemaCommand prints the schema of the provider configured with a source file, including the signatures
+// of its functions, in the format of tofu providers schema -json, e.g.:
+//
+//	terraform-provider-go schema lib.go
+func schemaCommand(args []string) error {
+	flags := flag.NewFlagSet("schema", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: terraform-provider-go schema [-settings JSON] FILE")
+		flags.PrintDefaults()
+	}
+	settings := flags.String("settings", "", "the settings of the provider, as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("missing file")
+	}
+
+	provider, err := configureFile(flags.Arg(0), *settings)
+	if err != nil {
+		return err
+	}
+	schema, err := providerSchemaJSON(provider)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&jsonProviderSchemas{
+		FormatVersion:   "1.0",
+		ProviderSchemas: map[string]*jsonProviderSchema{providerAddress: schema},
+	})
+}
+
+// providerSchemaJSON returns the JSON representation of the schema of the configured provider.
+func providerSchemaJSON(provider *FunctionProvider) (*jsonProviderSchema, error) {
+	ctx := context.Background()
+	schema, err := provider.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		return nil, err
+	}
+	// The provider schema only has the static functions, GetFunctions has the configured ones too.
+	functions, err := provider.GetFunctions(ctx, &tfprotov6.GetFunctionsRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &jsonProviderSchema{
+		ResourceSchemas:          map[string]*jsonSchema{},
+		DataSourceSchemas:        map[string]*jsonSchema{},
+		EphemeralResourceSchemas: map[string]*jsonSchema{},
+		Functions:                map[string]*jsonFunction{},
+	}
+	if out.Provider, err = schemaJSON(schema.Provider); err != nil {
+		return nil, fmt.Errorf("provider: %w", err)
+	}
+	for _, schemas := range []struct {
+		in  map[string]*tfprotov6.Schema
+		out map[string]*jsonSchema
+	}{
+		{schema.ResourceSchemas, out.ResourceSchemas},
+		{schema.DataSourceSchemas, out.DataSourceSchemas},
+		{schema.EphemeralResourceSchemas, out.EphemeralResourceSchemas},
+	} {
+		for name, schema := range schemas.in {
+			if schemas.out[name], err = schemaJSON(schema); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	for name, fn := range functions.Functions {
+		if out.Functions[name], err = functionJSON(fn); err != nil {
+			return nil, fmt.Errorf("function %s: %w", name, err)
+		}
+	}
+	return out, nil
+}
+
+func schemaJSON(schema *tfprotov6.Schema) (*jsonSchema, error) {
+	block := &jsonBlock{
+		Attributes:      map[string]*jsonAttribute{},
+		Description:     schema.Block.Description,
+		DescriptionKind: "plain",
+		Deprecated:      schema.Block.Deprecated,
+	}
+	for _, attribute := range schema.Block.Attributes {
+		attributeType, err := typeJSON(attribute.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", attribute.Name, err)
+		}
+		block.Attributes[attribute.Name] = &jsonAttribute{
+			Type:            attributeType,
+			Description:     attribute.Description,
+			DescriptionKind: "plain",
+			Deprecated:      attribute.Deprecated,
+			Required:        attribute.Required,
+			Optional:        attribute.Optional,
+			Computed:        attribute.Computed,
+			Sensitive:       attribute.Sensitive,
+		}
+	}
+	return &jsonSchema{Version: schema.Version, Block: block}, nil
+}
+
+func functionJSON(fn *tfprotov6.Function) (*jsonFunction, error) {
+	param := func(param *tfprotov6.FunctionParameter) (*jsonFunctionParam, error) {
+		paramType, err := typeJSON(param.Type)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+		return &jsonFunctionParam{
+			Name:        param.Name,
+			Description: param.Description,
+			IsNullable:  param.AllowNullValue,
+			Type:        paramType,
+		}, nil
+	}
+
+	returnType, err := typeJSON(fn.Return.Type)
+	if err != nil {
+		return nil, fmt.Errorf("return: %w", err)
+	}
+	out := &jsonFunction{
+		Description:        fn.Description,
+		Summary:            fn.Summary,
+		DeprecationMessage: fn.DeprecationMessage,
+		ReturnType:         returnType,
+	}
+	for _, p := range fn.Parameters {
+		jsonParam, err := param(p)
+		if err != nil {
+			return nil, err
+		}
+		out.Parameters = append(out.Parameters, jsonParam)
+	}
+	if fn.VariadicParameter != nil {
+		if out.VariadicParameter, err = param(fn.VariadicParameter); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// typeJSON returns the JSON representation of a type, as used by Tofu.
+func typeJSON(t tftypes.Type) (json.RawMessage, error) {
+	ctyType, err := TFTypeToCtyType(t)
+	if err != nil {
+		return nil, err
+	}
+	return ctyType.MarshalJSON()
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestSchemaJSON(t *testing.T) {
+	t.Setenv(libraryEnv, filepath.Join("testdata", "schema", "static.go"))
+	provider, err := configureFile(filepath.Join("testdata", "schema", "lib.go"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := providerSchemaJSON(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.MarshalIndent(&jsonProviderSchemas{
+		FormatVersion:   "1.0",
+		ProviderSchemas: map[string]*jsonProviderSchema{providerAddress: schema},
+	}, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireGolden(t, filepath.Join("testdata", "schema", "golden.json"), append(got, '\n'))
+}
@@ -0,0 +1,24 @@
+//go:build protocol5
+
+package main
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tf5server"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-mux/tf6to5server"
+)
+
+// serve serves the provider over protocol version 5, for older clients.
+// The protocol version 6 provider is translated, which works as its schema doesn't use nested attributes.
+func serve(address string, provider func() tfprotov6.ProviderServer) error {
+	server, err := tf6to5server.DowngradeServer(context.Background(), provider)
+	if err != nil {
+		return err
+	}
+	return tf5server.Serve(address, func() tfprotov5.ProviderServer {
+		return server
+	})
+}
@@ -0,0 +1,66 @@
+//go:build protocol5
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-mux/tf6to5server"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestProtocol5(t *testing.T) {
+	ctx := context.Background()
+	p := startProvider(t, "")
+	server, err := tf6to5server.DowngradeServer(ctx, func() tfprotov6.ProviderServer { return p.server })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := server.GetProviderSchema(ctx, &tfprotov5.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schema.Diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %s: %s", schema.Diagnostics[0].Summary, schema.Diagnostics[0].Detail)
+	}
+	v6Schema, err := p.server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := p.dynamicValue(v6Schema.Provider, map[string]cty.Value{
+		"go": cty.StringVal("package lib\nfunc Hello(name string) string { return \"Hello, \" + name + \"!\" }"),
+	})
+	configureResp, err := server.ConfigureProvider(ctx, &tfprotov5.ConfigureProviderRequest{
+		Config: &tfprotov5.DynamicValue{MsgPack: config.MsgPack, JSON: config.JSON},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configureResp.Diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %s: %s", configureResp.Diagnostics[0].Summary, configureResp.Diagnostics[0].Detail)
+	}
+
+	argument, err := CtyToProto(cty.String, cty.StringVal("world"))
+	if err != nil {
+		t.Fatal(formatCtyError(err))
+	}
+	resp, err := server.CallFunction(ctx, &tfprotov5.CallFunctionRequest{
+		Name:      "hello",
+		Arguments: []*tfprotov5.DynamicValue{{MsgPack: argument.MsgPack, JSON: argument.JSON}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("hello: %s", resp.Error.Text)
+	}
+	result, err := ProtoToCty(cty.String, &tfprotov6.DynamicValue{MsgPack: resp.Result.MsgPack, JSON: resp.Result.JSON})
+	if err != nil {
+		t.Fatal(formatCtyError(err))
+	}
+	requireEqual(t, result, cty.StringVal("Hello, world!"))
+}
@@ -0,0 +1,13 @@
+//go:build !protocol5
+
+package main
+
+import (
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6/tf6server"
+)
+
+// serve serves the provider over protocol version 6, which requires OpenTofu or Terraform 1.0 or later.
+func serve(address string, provider func() tfprotov6.ProviderServer) error {
+	return tf6server.Serve(address, provider)
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/zclconf/go-cty/cty"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+const (
+	// starlarkMaxSteps caps the number of computation steps a single call may take, after which it's cancelled.
+	starlarkMaxSteps = 10_000_000
+	// starlarkCallTimeout caps the time a single call may run for, after which it's cancelled.
+	starlarkCallTimeout = 10 * time.Second
+)
+
+// StarlarkBackend runs Starlark functions, a hermetic and deterministic Python dialect.
+type StarlarkBackend struct{}
+
+func (b *StarlarkBackend) Name() string {
+	return "starlark"
+}
+
+func (b *StarlarkBackend) Description() string {
+	return "Starlark source code, whose top-level functions become provider functions."
+}
+
+func (b *StarlarkBackend) Load(source string, settings cty.Value, opts ConvertOptions) (Library, []*tfprotov6.Diagnostic) {
+	functions, diags := StarlarkFunctions(source, settings, opts)
+	if len(diags) > 0 {
+		return nil, diags
+	}
+	return FunctionLibrary(functions), nil
+}
+
+// starlarkSignature is the signature declared for a Starlark function with the signature builtin.
+type starlarkSignature struct {
+	params      []string
+	returns     string
+	description string
+}
+
+// StarlarkFunctions executes the Starlark code and returns its top-level functions.
+// Functions whose name starts with an underscore are private.
+// The types of a function are declared with the signature builtin, using Tofu type constraints, e.g.:
+//
+//	def hello(name):
+//	    return "Hello, " + name + "!"
+//
+//	signature(hello, params = ["string"], returns = "string")
+//
+// Parameters and results of functions without a signature are dynamic.
+// Functions fail by calling fail(). The settings are available as the settings global.
+func StarlarkFunctions(code string, settings cty.Value, opts ConvertOptions) (map[string]*Function, []*tfprotov6.Diagnostic) {
+	goSettings, err := ctyToAny(settings, nil)
+	var starlarkSettings starlark.Value
+	if err == nil {
+		starlarkSettings, err = goToStarlark(goSettings)
+	}
+	if err != nil {
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity:  tfprotov6.DiagnosticSeverityError,
+			Summary:   "Invalid settings",
+			Detail:    formatCtyError(err).Error(),
+			Attribute: tftypes.NewAttributePath().WithAttributeName("settings"),
+		}}
+	}
+	// Functions may run concurrently, so they must not modify the settings.
+	starlarkSettings.Freeze()
+
+	signatures := map[*starlark.Function]*starlarkSignature{}
+	predeclared := starlark.StringDict{
+		"settings": starlarkSettings,
+		"signature": starlark.NewBuiltin("signature", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var fn *starlark.Function
+			var params *starlark.List
+			var returns, description string
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "fn", &fn, "params?", &params, "returns?", &returns, "description?", &description); err != nil {
+				return nil, err
+			}
+			if _, ok := signatures[fn]; ok {
+				return nil, fmt.Errorf("%s: %s already has a signature", b.Name(), fn.Name())
+			}
+
+			signature := &starlarkSignature{returns: returns, description: description}
+			if params != nil {
+				for i := 0; i < params.Len(); i++ {
+					param, ok := starlark.AsString(params.Index(i))
+					if !ok {
+						return nil, fmt.Errorf("%s: parameter %d: type must be a string", b.Name(), i+1)
+					}
+					signature.params = append(signature.params, param)
+				}
+			}
+			signatures[fn] = signature
+			return fn, nil
+		}),
+	}
+
+	thread, cancel := newStarlarkThread("load")
+	defer cancel()
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, "starlark", code, predeclared)
+	if err != nil {
+		if evalErr, ok := err.(*starlark.EvalError); ok {
+			err = fmt.Errorf("%s", evalErr.Backtrace())
+		}
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Failed to evaluate Starlark code",
+			Detail:   err.Error(),
+		}}
+	}
+
+	functions := map[string]*Function{}
+	for name, value := range globals {
+		starlarkFn, ok := value.(*starlark.Function)
+		if !ok || strings.HasPrefix(name, "_") {
+			continue
+		}
+
+		fn, err := starlarkSignatureToTFFunction(starlarkFn, signatures[starlarkFn])
+		if err != nil {
+			return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+				Severity: tfprotov6.DiagnosticSeverityError,
+				Summary:  "Invalid Starlark function",
+				Detail:   fmt.Sprintf("%s: %s", name, err),
+			}}
+		}
+
+		name := name
+		functions[name] = &Function{
+			Function: *fn,
+			Impl: func(args []*tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, *tfprotov6.FunctionError) {
+				starlarkArgs := make(starlark.Tuple, len(args))
+				for i, arg := range args {
+					goArg, err := ProtoToGo(fn.Parameters[i].Type, reflect.TypeFor[any](), arg, opts)
+					if err == nil {
+						starlarkArgs[i], err = goToStarlark(goArg)
+					}
+					if err != nil {
+						argument := int64(i)
+						return nil, &tfprotov6.FunctionError{
+							Text:             err.Error(),
+							FunctionArgument: &argument,
+						}
+					}
+				}
+
+				// Frozen globals are safe for concurrent use, threads are not.
+				thread, cancel := newStarlarkThread(name)
+				defer cancel()
+				result, err := starlark.Call(thread, starlarkFn, starlarkArgs, nil)
+				if err != nil {
+					if evalErr, ok := err.(*starlark.EvalError); ok {
+						err = fmt.Errorf("%s", evalErr.Msg)
+					}
+					return nil, &tfprotov6.FunctionError{
+						Text: err.Error(),
+					}
+				}
+
+				goResult, err := starlarkToGo(result)
+				if err != nil {
+					return nil, &tfprotov6.FunctionError{
+						Text: err.Error(),
+					}
+				}
+				out, err := GoToProto(fn.Return.Type, goResult, opts)
+				if err != nil {
+					return nil, &tfprotov6.FunctionError{
+						Text: err.Error(),
+					}
+				}
+				return out, nil
+			},
+		}
+	}
+
+	return functions, nil
+}
+
+// newStarlarkThread returns a thread which is cancelled once it exceeds starlarkMaxSteps or starlarkCallTimeout,
+// along with the function to call once it's done.
+func newStarlarkThread(name string) (*starlark.Thread, context.CancelFunc) {
+	thread := &starlark.Thread{Name: name}
+	thread.SetMaxExecutionSteps(starlarkMaxSteps)
+	thread.OnMaxSteps = func(thread *starlark.Thread) {
+		thread.Cancel(fmt.Sprintf("exceeded the limit of %d steps", starlarkMaxSteps))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), starlarkCallTimeout)
+	stop := context.AfterFunc(ctx, func() {
+		thread.Cancel(fmt.Sprintf("exceeded the time limit of %s", starlarkCallTimeout))
+	})
+	return thread, func() {
+		stop()
+		cancel()
+	}
+}
+
+func starlarkSignatureToTFFunction(fn *starlark.Function, signature *starlarkSignature) (*tfprotov6.Function, error) {
+	if fn.HasVarargs() || fn.HasKwargs() || fn.NumKwonlyParams() > 0 {
+		return nil, fmt.Errorf("functions must only have positional parameters")
+	}
+	if signature == nil {
+		signature = &starlarkSignature{}
+	}
+	if signature.params != nil && len(signature.params) != fn.NumParams() {
+		return nil, fmt.Errorf("signature has %d parameters, function has %d", len(signature.params), fn.NumParams())
+	}
+
+	var parameters []*tfprotov6.FunctionParameter
+	for i := 0; i < fn.NumParams(); i++ {
+		name, _ := fn.Param(i)
+		var paramType tftypes.Type = tftypes.DynamicPseudoType
+		if signature.params != nil {
+			var err error
+			paramType, err = ParseTypeExpr(signature.params[i])
+			if err != nil {
+				return nil, fmt.Errorf("parameter %s: %w", name, err)
+			}
+		}
+		parameters = append(parameters, &tfprotov6.FunctionParameter{
+			Name: name,
+			Type: paramType,
+		})
+	}
+
+	var returnType tftypes.Type = tftypes.DynamicPseudoType
+	if signature.returns != "" {
+		var err error
+		returnType, err = ParseTypeExpr(signature.returns)
+		if err != nil {
+			return nil, fmt.Errorf("returns: %w", err)
+		}
+	}
+
+	return &tfprotov6.Function{
+		Parameters:  parameters,
+		Return:      &tfprotov6.FunctionReturn{Type: returnType},
+		Description: signature.description,
+	}, nil
+}
+
+// goToStarlark converts the generic Go representation of a value to a Starlark value.
+// Whole numbers become Starlark ints, so that they can be used as indices.
+func goToStarlark(value any) (starlark.Value, error) {
+	switch value := value.(type) {
+	case nil:
+		return starlark.None, nil
+	case string:
+		return starlark.String(value), nil
+	case bool:
+		return starlark.Bool(value), nil
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+			return starlark.MakeInt64(int64(value)), nil
+		}
+		return starlark.Float(value), nil
+	case []any:
+		elems := make([]starlark.Value, len(value))
+		for i, elem := range value {
+			var err error
+			if elems[i], err = goToStarlark(elem); err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		return starlark.NewList(elems), nil
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		dict := starlark.NewDict(len(value))
+		for _, key := range keys {
+			elem, err := goToStarlark(value[key])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			if err := dict.SetKey(starlark.String(key), elem); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	default:
+		return nil, fmt.Errorf("unexpected generic value of type %T", value)
+	}
+}
+
+// starlarkToGo converts a Starlark value to its generic Go representation.
+func starlarkToGo(value starlark.Value) (any, error) {
+	switch value := value.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.String:
+		return string(value), nil
+	case starlark.Bool:
+		return bool(value), nil
+	case starlark.Int:
+		if i, ok := value.Int64(); ok {
+			return i, nil
+		}
+		return float64(value.Float()), nil
+	case starlark.Float:
+		return float64(value), nil
+	case starlark.Indexable:
+		out := make([]any, value.Len())
+		for i := range out {
+			elem, err := starlarkToGo(value.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out[i] = elem
+		}
+		return out, nil
+	case *starlark.Dict:
+		out := make(map[string]any, value.Len())
+		for _, item := range value.Items() {
+			key, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("dict keys must be strings, got %s", item[0].Type())
+			}
+			elem, err := starlarkToGo(item[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			out[key] = elem
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported Starlark type %s", value.Type())
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestStarlarkSignatureErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		summary string
+		detail  string
+	}{
+		{
+			name:    "twice",
+			code:    "def f(s):\n    return s\n\nsignature(f, params = [\"string\"], returns = \"string\")\nsignature(f, returns = \"string\")\n",
+			summary: "Failed to evaluate Starlark code",
+			detail:  "signature: f already has a signature",
+		},
+		{
+			name:    "parameter type",
+			code:    "def f(s):\n    return s\n\nsignature(f, params = [1], returns = \"string\")\n",
+			summary: "Failed to evaluate Starlark code",
+			detail:  "signature: parameter 1: type must be a string",
+		},
+		{
+			name:    "not a function",
+			code:    "signature(\"f\", returns = \"string\")\n",
+			summary: "Failed to evaluate Starlark code",
+			detail:  "signature: for parameter fn: got string, want function",
+		},
+		{
+			name:    "parameter count",
+			code:    "def f(s):\n    return s\n\nsignature(f, params = [\"string\", \"string\"], returns = \"string\")\n",
+			summary: "Invalid Starlark function",
+			detail:  "f: signature has 2 parameters, function has 1",
+		},
+		{
+			name:    "invalid type",
+			code:    "def f(s):\n    return s\n\nsignature(f, params = [\"text\"], returns = \"string\")\n",
+			summary: "Invalid Starlark function",
+			detail:  "f: parameter s:",
+		},
+		{
+			name:    "varargs",
+			code:    "def f(*args):\n    return args\n",
+			summary: "Invalid Starlark function",
+			detail:  "f: functions must only have positional parameters",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diags := startProvider(t, "").configure(map[string]cty.Value{"starlark": cty.StringVal(test.code)})
+			requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, test.summary, test.detail)
+		})
+	}
+}
+
+func TestStarlarkErrors(t *testing.T) {
+	p := configuredProvider(t, map[string]cty.Value{
+		"settings": cty.ObjectVal(map[string]cty.Value{"env": cty.StringVal("prod")}),
+		"starlark": cty.StringVal(`
+def env():
+    return settings["env"]
+
+def set_env(env):
+    settings["env"] = env
+    return env
+
+def check(n):
+    if n < 0:
+        fail("negative number", n)
+    return n
+
+def spin():
+    n = 0
+    for i in range(100000000):
+        n += i
+    return n
+
+signature(set_env, params = ["string"], returns = "string")
+signature(check, params = ["number"], returns = "number")
+signature(spin, params = [], returns = "number")
+`),
+	})
+
+	requireEqual(t, p.mustCall("check", cty.NumberIntVal(1)), cty.NumberIntVal(1))
+	tests := []struct {
+		name string
+		fn   string
+		args []cty.Value
+		text string
+	}{
+		{name: "frozen settings", fn: "set_env", args: []cty.Value{cty.StringVal("dev")}, text: "cannot insert into frozen hash table"},
+		{name: "fail", fn: "check", args: []cty.Value{cty.NumberIntVal(-1)}, text: "fail: negative number -1"},
+		{name: "step limit", fn: "spin", text: "exceeded the limit of 10000000 steps"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, funcErr := p.call(test.fn, test.args...)
+			if funcErr == nil || !strings.Contains(funcErr.Text, test.text) {
+				t.Fatalf("got error %v, want it to contain %q", funcErr, test.text)
+			}
+			if funcErr.FunctionArgument != nil {
+				t.Errorf("got error about argument %d, want a function error", *funcErr.FunctionArgument)
+			}
+		})
+	}
+	// The settings are unchanged.
+	requireEqual(t, p.mustCall("env"), cty.StringVal("prod"))
+}
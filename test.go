@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/traefik/yaegi/interp"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// testCommand runs the tests of a source file like go test, and exits with its status, e.g.:
+//
+//	terraform-provider-go test lib.go lib_test.go lib_test.hcl
+//
+// The TestXxx and ExampleXxx functions of _test.go files are run in the interpreter along with the Go code,
+// and the tests of .hcl files call the functions through the provider, like Tofu does.
+func testCommand(args []string) error {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: terraform-provider-go test [-run REGEXP] [-v] [-settings JSON] FILE TEST FILES...")
+		flags.PrintDefaults()
+	}
+	run := flags.String("run", "", "run only the tests and examples matching the regular expression")
+	verbose := flags.Bool("v", false, "log all tests as they are run")
+	settings := flags.String("settings", "", "the settings of the provider, passed to Init before Go tests and configuring the provider of HCL tests, as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return errors.New("missing file or test files")
+	}
+	path := flags.Arg(0)
+
+	var goTestFiles, hclTestFiles []string
+	for _, testFile := range flags.Args()[1:] {
+		switch {
+		case strings.HasSuffix(testFile, "_test.go"):
+			goTestFiles = append(goTestFiles, testFile)
+		case filepath.Ext(testFile) == ".hcl":
+			hclTestFiles = append(hclTestFiles, testFile)
+		default:
+			return fmt.Errorf("%s: unknown test file, expected a _test.go or .hcl file", testFile)
+		}
+	}
+
+	var tests []testing.InternalTest
+	var examples []testing.InternalExample
+	if len(goTestFiles) > 0 {
+		if filepath.Ext(path) != ".go" {
+			return fmt.Errorf("%s: Go tests need Go code", path)
+		}
+		var err error
+		tests, examples, err = goTests(path, goTestFiles, *settings)
+		if err != nil {
+			return err
+		}
+	}
+	if len(hclTestFiles) > 0 {
+		provider, err := configureFile(path, *settings)
+		if err != nil {
+			return err
+		}
+		for _, testFile := range hclTestFiles {
+			test, err := hclTests(provider, testFile)
+			if err != nil {
+				return err
+			}
+			tests = append(tests, test)
+		}
+	}
+
+	// Unlike testing.Main, the tests are run without exiting, leaving it to the caller.
+	// The test flags are parsed from the options of the command, as m.Run would parse os.Args otherwise.
+	testing.Init()
+	if err := flag.CommandLine.Parse(testArgs(*run, *verbose)); err != nil {
+		return err
+	}
+	m := testing.MainStart(testDeps{}, tests, nil, nil, examples)
+	if m.Run() != 0 {
+		return errors.New("tests failed")
+	}
+	return nil
+}
+
+// testArgs returns the arguments of the test flags matching the options of the test command.
+func testArgs(run string, verbose bool) []string {
+	return []string{"-test.run=" + run, "-test.v=" + strconv.FormatBool(verbose)}
+}
+
+// testDeps implements the dependencies of testing.MainStart, matching test names with regular expressions,
+// without profiles, fuzzing or coverage.
+type testDeps struct{}
+
+// testCorpusEntry is the type of the entries of fuzzing corpora, like the corpusEntry alias of testing.
+type testCorpusEntry = struct {
+	Parent     string
+	Path       string
+	Data       []byte
+	Values     []any
+	Generation int
+	IsSeed     bool
+}
+
+func (testDeps) ImportPath() string                              { return "lib" }
+func (testDeps) ModulePath() string                              { return "" }
+func (testDeps) MatchString(pat, str string) (bool, error)       { return regexp.MatchString(pat, str) }
+func (testDeps) SetPanicOnExit0(bool)                            {}
+func (testDeps) StartCPUProfile(io.Writer) error                 { return errTestUnsupported }
+func (testDeps) StopCPUProfile()                                 {}
+func (testDeps) StartTestLog(io.Writer)                          {}
+func (testDeps) StopTestLog() error                              { return nil }
+func (testDeps) WriteProfileTo(string, io.Writer, int) error     { return errTestUnsupported }
+func (testDeps) CheckCorpus([]any, []reflect.Type) error         { return nil }
+func (testDeps) ResetCoverage()                                  {}
+func (testDeps) SnapshotCoverage()                               {}
+func (testDeps) RunFuzzWorker(func(testCorpusEntry) error) error { return errTestUnsupported }
+func (testDeps) ReadCorpus(string, []reflect.Type) ([]testCorpusEntry, error) {
+	return nil, errTestUnsupported
+}
+func (testDeps) CoordinateFuzzing(time.Duration, int64, time.Duration, int64, int, []testCorpusEntry, []reflect.Type, string, string) error {
+	return errTestUnsupported
+}
+func (testDeps) InitRuntimeCoverage() (string, func(string, string) (string, error), func() float64) {
+	return "", nil, nil
+}
+
+var errTestUnsupported = errors.New("profiles and fuzzing aren't supported by the test command")
+
+// currentStdout writes to the current os.Stdout, which testing replaces to check the output of examples.
+type currentStdout struct{}
+
+func (currentStdout) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+// goTests evaluates the Go code along with its test files, which must be in the lib package too,
+// calls its Init function with the settings if any, like the provider does,
+// and returns their tests and the examples with an output comment, in source order.
+func goTests(path string, testFiles []string, settings string) ([]testing.InternalTest, []testing.InternalExample, error) {
+	opts := ConvertOptions{Types: TypeRegistry{}}
+	interpreter, diags := newGoInterpreter(interp.Options{Stdout: currentStdout{}}, opts.Types, ResourceRegistry{}, EphemeralResourceRegistry{})
+	if len(diags) > 0 {
+		return nil, nil, fmt.Errorf("%s: %s", diags[0].Summary, diags[0].Detail)
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, file := range append([]string{path}, testFiles...) {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		parsed, err := parser.ParseFile(fset, file, source, parser.ParseComments)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, parsed)
+		if _, err := interpreter.Eval(string(source)); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	exports := interpreter.Symbols("lib")["lib"]
+	// lookup returns the exported function of the lib package, which may not be there if a test file has another package.
+	lookup := func(name string) (reflect.Value, error) {
+		if export, ok := exports[name]; ok && export.IsValid() {
+			return export, nil
+		}
+		return reflect.Value{}, fmt.Errorf("%s must be in package lib", name)
+	}
+
+	if init, ok := exports["Init"]; ok && init.Kind() == reflect.Func {
+		if err := goInit(init, settings, opts); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	var tests []testing.InternalTest
+	for _, file := range files[1:] {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil {
+				continue
+			}
+			if _, ok := cutExportPrefix(fn.Name.Name, "Test"); !ok {
+				continue
+			}
+			export, err := lookup(fn.Name.Name)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", fset.Position(fn.Pos()), err)
+			}
+			test, ok := export.Interface().(func(*testing.T))
+			if !ok {
+				return nil, nil, fmt.Errorf("%s: %s must have the signature func(t *testing.T)", fset.Position(fn.Pos()), fn.Name.Name)
+			}
+			tests = append(tests, testing.InternalTest{Name: fn.Name.Name, F: test})
+		}
+	}
+
+	var examples []testing.InternalExample
+	for _, example := range doc.Examples(files[1:]...) {
+		if example.Output == "" && !example.EmptyOutput {
+			// Like go test, examples without an output comment are compiled but not run.
+			continue
+		}
+		name := "Example" + example.Name
+		export, err := lookup(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		fn, ok := export.Interface().(func())
+		if !ok {
+			return nil, nil, fmt.Errorf("%s must have the signature func()", name)
+		}
+		examples = append(examples, testing.InternalExample{
+			Name:      name,
+			F:         fn,
+			Output:    example.Output,
+			Unordered: example.Unordered,
+		})
+	}
+	return tests, examples, nil
+}
+
+// goInit calls the Init function of the Go code with the JSON settings, which are null if empty.
+func goInit(init reflect.Value, settings string, opts ConvertOptions) error {
+	value := cty.NullVal(cty.DynamicPseudoType)
+	if settings != "" {
+		var err error
+		if value, err = jsonToCty(cty.DynamicPseudoType, []byte(settings)); err != nil {
+			return fmt.Errorf("settings: %w", formatCtyError(err))
+		}
+	}
+	diags := checkGoInit(init.Type(), opts)
+	if len(diags) == 0 {
+		diags = (&GoLibrary{init: init, opts: opts}).Init(value)
+	}
+	if len(diags) > 0 {
+		return fmt.Errorf("%s: %s", diags[0].Summary, diags[0].Detail)
+	}
+	return nil
+}
+
+// hclTestFile is a file of table tests, e.g.:
+//
+//	test "greets" {
+//	  call = hello("papaya")
+//	  want = "Hello, papaya!"
+//	}
+//
+//	test "fails on empty names" {
+//	  call  = hello("")
+//	  error = "empty name"
+//	}
+type hclTestFile struct {
+	Tests []*hclTest `hcl:"test,block"`
+}
+
+type hclTest struct {
+	Name string `hcl:"name,label"`
+	// Call is the expression calling the functions.
+	Call hcl.Expression `hcl:"call"`
+	// Want is the expected value of Call, compared after converting it to the type of Call.
+	Want hcl.Expression `hcl:"want,optional"`
+	// Error is a regular expression matching the expected error of Call, if any.
+	Error string `hcl:"error,optional"`
+}
+
+// hclTests returns the test running the tests of an HCL file as subtests.
+func hclTests(provider *FunctionProvider, path string) (testing.InternalTest, error) {
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	if diags.HasErrors() {
+		return testing.InternalTest{}, diags
+	}
+	var testFile hclTestFile
+	if diags := gohcl.DecodeBody(file.Body, nil, &testFile); diags.HasErrors() {
+		return testing.InternalTest{}, diags
+	}
+
+	signatures, err := provider.GetFunctions(context.Background(), &tfprotov6.GetFunctionsRequest{})
+	if err != nil {
+		return testing.InternalTest{}, err
+	}
+	functions := map[string]function.Function{}
+	for name, fn := range signatures.Functions {
+		functions[name] = ctyFunction(provider, name, fn)
+	}
+	ctx := &hcl.EvalContext{Functions: functions}
+
+	return testing.InternalTest{
+		Name: filepath.Base(path),
+		F: func(t *testing.T) {
+			for _, test := range testFile.Tests {
+				t.Run(test.Name, func(t *testing.T) {
+					got, diags := test.Call.Value(ctx)
+					if test.Error != "" {
+						if !diags.HasErrors() {
+							t.Fatalf("%s: got %s, want an error matching %q", test.Call.Range(), ctyString(got), test.Error)
+						}
+						if matched, err := regexp.MatchString(test.Error, diags.Error()); err != nil || !matched {
+							t.Fatalf("%s: got error %q, want an error matching %q", test.Call.Range(), diags.Error(), test.Error)
+						}
+						return
+					}
+					if diags.HasErrors() {
+						t.Fatal(diags.Error())
+					}
+					want, diags := test.Want.Value(ctx)
+					if diags.HasErrors() {
+						t.Fatal(diags.Error())
+					}
+					if !ctyEqual(got, want) {
+						t.Errorf("%s: got %s, want %s", test.Call.Range(), ctyString(got), ctyString(want))
+					}
+				})
+			}
+		},
+	}, nil
+}
+
+// ctyEqual reports whether the values are equal, once want is converted to the type of got.
+func ctyEqual(got, want cty.Value) bool {
+	want, err := convert.Convert(want, got.Type())
+	if err != nil {
+		return false
+	}
+	equal := got.Equals(want)
+	return equal.IsKnown() && equal.True()
+}
+
+// ctyString formats a value as JSON.
+func ctyString(value cty.Value) string {
+	out, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return value.GoString()
+	}
+	return string(out)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+const testGoLibrary = `package lib
+
+var env string
+
+func Init(settings struct{ Env string }) error {
+	env = settings.Env
+	return nil
+}
+
+func Env() string { return env }
+`
+
+func TestGoTests(t *testing.T) {
+	path := writeSource(t, "lib.go", testGoLibrary)
+	testFile := writeSource(t, "lib_test.go", `package lib
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	if Env() != "prod" {
+		t.Fatalf("got %q, want prod", Env())
+	}
+}
+
+func ExampleEnv() {
+	fmt.Println(Env())
+	// Output: prod
+}
+
+func ExampleEnv_unchecked() {
+	fmt.Println(Env())
+}
+`)
+	tests, examples, err := goTests(path, []string{testFile}, `{"env": "prod"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tests) != 1 || tests[0].Name != "TestEnv" {
+		t.Fatalf("got tests %v, want TestEnv", tests)
+	}
+	// The interpreted test runs as a subtest, checking that Init was called with the settings.
+	t.Run(tests[0].Name, tests[0].F)
+	if len(examples) != 1 || examples[0].Name != "ExampleEnv" || examples[0].Output != "prod\n" {
+		t.Fatalf("got examples %v, want ExampleEnv only", examples)
+	}
+}
+
+func TestGoTestsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		testFile string
+		settings string
+		err      string
+	}{
+		{
+			name:     "other package",
+			code:     "package lib",
+			testFile: "package lib_test\nimport \"testing\"\nfunc TestOther(t *testing.T) {}",
+			err:      "TestOther must be in package lib",
+		},
+		{
+			name:     "test signature",
+			code:     "package lib",
+			testFile: "package lib\nfunc TestNothing() {}",
+			err:      "TestNothing must have the signature func(t *testing.T)",
+		},
+		{
+			name:     "example in other package",
+			code:     "package lib",
+			testFile: "package lib_test\nimport \"fmt\"\nfunc ExampleOther() {\n\tfmt.Println(1)\n\t// Output: 1\n}",
+			err:      "ExampleOther must be in package lib",
+		},
+		{
+			name:     "missing settings",
+			code:     testGoLibrary,
+			testFile: "package lib",
+			err:      "Missing settings",
+		},
+		{
+			name:     "invalid settings",
+			code:     testGoLibrary,
+			testFile: "package lib",
+			settings: `{"env": `,
+			err:      "settings:",
+		},
+		{
+			name:     "invalid Init",
+			code:     "package lib\nfunc Init(settings string) {}",
+			testFile: "package lib",
+			settings: `"prod"`,
+			err:      "Invalid Init function",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeSource(t, "lib.go", test.code)
+			_, _, err := goTests(path, []string{writeSource(t, "lib_test.go", test.testFile)}, test.settings)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("got error %v, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestTestArgs(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	run := flags.String("test.run", "", "")
+	verbose := flags.Bool("test.v", false, "")
+	if err := flags.Parse(testArgs("TestEnv|Example", true)); err != nil {
+		t.Fatal(err)
+	}
+	if *run != "TestEnv|Example" || !*verbose || flags.NArg() != 0 {
+		t.Errorf("got -test.run=%q -test.v=%t and arguments %v", *run, *verbose, flags.Args())
+	}
+}
@@ -0,0 +1,25 @@
+// lib.go is the source file of the docs test, whose pages are in the functions directory along with the examples of lib_test.go.
+package lib
+
+import "strings"
+
+// Hello greets the given name.
+//
+// The greeting ends with an exclamation mark.
+func Hello(name string) string {
+	return "Hello, " + name + "!"
+}
+
+// Join joins the names with the separator, if any.
+//
+// Deprecated: use the join function of Tofu instead.
+func Join(names []string, sep *string) string {
+	if sep == nil {
+		return strings.Join(names, ", ")
+	}
+	return strings.Join(names, *sep)
+}
+
+func Len(s string) int {
+	return len(s)
+}
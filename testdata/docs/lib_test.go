@@ -0,0 +1,18 @@
+package lib
+
+import "fmt"
+
+func ExampleHello() {
+	fmt.Println(Hello("papaya"))
+	// Output: Hello, papaya!
+}
+
+func ExampleHello_twice() {
+	fmt.Println(Hello(Hello("papaya")))
+	// Output: Hello, Hello, papaya!!
+}
+
+func ExampleJoin() {
+	fmt.Println(Join([]string{"a", "b"}, nil))
+	// Output: a, b
+}
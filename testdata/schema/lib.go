@@ -0,0 +1,39 @@
+// lib.go is the source file of the schema test, whose schema is golden.json along with static.go.
+package lib
+
+import (
+	"net/netip"
+	"time"
+)
+
+type Server struct {
+	Name    string
+	Address netip.Addr
+	Tags    map[string]string
+	Port    *int
+}
+
+// Hello greets the given name.
+func Hello(name string) string {
+	return "Hello, " + name + "!"
+}
+
+// Servers returns the servers of the given names, with an optional port.
+func Servers(names []string, port *int) []Server {
+	var servers []Server
+	for _, name := range names {
+		servers = append(servers, Server{Name: name, Port: port})
+	}
+	return servers
+}
+
+// Greet greets the given name.
+//
+// Deprecated: use Hello instead.
+func Greet(name string) string {
+	return Hello(name)
+}
+
+func Later(t time.Time, d time.Duration) time.Time {
+	return t.Add(d)
+}
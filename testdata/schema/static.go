@@ -0,0 +1,49 @@
+// static.go is the static library of the schema test, given by the TOFU_PROVIDER_GO_LIBRARY environment variable.
+package lib
+
+import (
+	"time"
+	"tofu"
+)
+
+type Input struct {
+	Name string
+}
+
+type Output struct {
+	Greeting string
+}
+
+func DataGreeting(in Input) (Output, error) {
+	return Output{Greeting: "Hello, " + in.Name + "!"}, nil
+}
+
+type Bucket struct {
+	Name    string `tf:"name,forcenew"`
+	ID      string `tf:"id,computed"`
+	Version int    `tf:"version,computed,volatile"`
+}
+
+type bucketResource struct{}
+
+func (bucketResource) Create(plan any) (any, error)        { return plan, nil }
+func (bucketResource) Read(state any) (any, error)         { return state, nil }
+func (bucketResource) Update(prior, plan any) (any, error) { return plan, nil }
+func (bucketResource) Delete(state any) error              { return nil }
+func (bucketResource) Import(id string) (any, error)       { return Bucket{Name: id, ID: id}, nil }
+
+type Token struct {
+	Scope string
+	Value string `tf:"value,computed"`
+}
+
+type tokenResource struct{}
+
+func (tokenResource) Open(config any) (any, time.Time, error) { return config, time.Time{}, nil }
+func (tokenResource) Renew(result any) (time.Time, error)     { return time.Time{}, nil }
+func (tokenResource) Close(result any) error                  { return nil }
+
+func init() {
+	tofu.RegisterResource("bucket", Bucket{}, bucketResource{})
+	tofu.RegisterEphemeralResource("token", Token{}, tokenResource{})
+}
@@ -0,0 +1,65 @@
+;; lib.wat is a minimal module for the tests of the WebAssembly backend, and lib.wasm is its binary format,
+;; with these signatures in its tofu.signatures custom section:
+;;
+;;   {
+;;     "echo": {"params": ["string"], "returns": "string"},
+;;     "fail": {"params": [], "returns": "string"},
+;;     "settings": {"params": [], "returns": "any"},
+;;     "spin": {"params": [], "returns": "string"}
+;;   }
+(module
+  (import "wasi_snapshot_preview1" "environ_sizes_get" (func $environ_sizes_get (param i32 i32) (result i32)))
+  (import "wasi_snapshot_preview1" "environ_get" (func $environ_get (param i32 i32) (result i32)))
+
+  (memory (export "memory") 1)
+  (global $heap (mut i32) (i32.const 1024))
+
+  (data (i32.const 0) "{\"result\"")
+  (data (i32.const 16) "{\"result\":    ")
+  (data (i32.const 32) "{\"error\":\"failed\"}")
+
+  ;; tofu_alloc allocates memory from the heap, leaving room for the 9 bytes of {"result" before it for echo.
+  (func $alloc (export "tofu_alloc") (param $size i32) (result i32)
+    (local $ptr i32)
+    (local.set $ptr (i32.add (global.get $heap) (i32.const 9)))
+    (global.set $heap (i32.add (local.get $ptr) (local.get $size)))
+    (if (i32.gt_u (global.get $heap) (i32.shl (memory.size) (i32.const 16)))
+      (then
+        (drop (memory.grow (i32.add (i32.shr_u (global.get $heap) (i32.const 16)) (i32.const 1))))))
+    (local.get $ptr))
+
+  ;; echo returns its argument, turning ["value"] into {"result":"value"} in place.
+  (func (export "echo") (param $ptr i32) (param $len i32) (result i64)
+    (memory.copy (i32.sub (local.get $ptr) (i32.const 9)) (i32.const 0) (i32.const 9))
+    (i32.store8 (local.get $ptr) (i32.const 58))
+    (i32.store8 (i32.sub (i32.add (local.get $ptr) (local.get $len)) (i32.const 1)) (i32.const 125))
+    (i64.or
+      (i64.shl (i64.extend_i32_u (i32.sub (local.get $ptr) (i32.const 9))) (i64.const 32))
+      (i64.extend_i32_u (i32.add (local.get $len) (i32.const 9)))))
+
+  ;; fail returns {"error":"failed"}.
+  (func (export "fail") (param i32 i32) (result i64)
+    (i64.const 0x20_0000_0012))
+
+  ;; settings returns the settings, turning the TOFU_SETTINGS={...} environment in place into {"result":    {...}}.
+  (func (export "settings") (param i32 i32) (result i64)
+    (local $buf i32)
+    (local $size i32)
+    (drop (call $environ_sizes_get (i32.const 64) (i32.const 68)))
+    (local.set $size (i32.load (i32.const 68)))
+    (local.set $buf (call $alloc (local.get $size)))
+    (drop (call $environ_get (i32.const 72) (local.get $buf)))
+    (memory.copy (local.get $buf) (i32.const 16) (i32.const 14))
+    (i32.store8 (i32.sub (i32.add (local.get $buf) (local.get $size)) (i32.const 1)) (i32.const 125))
+    (i64.or
+      (i64.shl (i64.extend_i32_u (local.get $buf)) (i64.const 32))
+      (i64.extend_i32_u (local.get $size))))
+
+  ;; spin calls a function forever, until it runs out of fuel.
+  (func $nop)
+  (func (export "spin") (param i32 i32) (result i64)
+    (loop $forever
+      (call $nop)
+      (br $forever))
+    (unreachable))
+)
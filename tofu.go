@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/traefik/yaegi/interp"
+)
+
+// TypeConverter converts values of a custom Go type to and from Tofu values.
+type TypeConverter struct {
+	// Type is the Tofu type the Go values are represented as.
+	Type tftypes.Type
+	// ToTF converts the Go value to the generic representation of Type,
+	// i.e. string, bool, int or float64, []any or map[string]any.
+	ToTF func(any) (any, error)
+	// FromTF converts the generic representation of a value of Type to the Go value.
+	FromTF func(any) (any, error)
+}
+
+// TypeRegistry holds the custom type converters registered by the Go code, keyed by the Go type.
+// It is consulted by the conversion functions before any of the built-in conversions.
+type TypeRegistry map[reflect.Type]*TypeConverter
+
+func (r TypeRegistry) register(value any, tfType tftypes.Type, toTF, fromTF func(any) (any, error)) {
+	t := reflect.TypeOf(value)
+	switch {
+	case t == nil:
+		panic("tofu.RegisterType: value must not be nil")
+	case tfType == nil:
+		panic(fmt.Sprintf("tofu.RegisterType: missing Tofu type for %s", t))
+	case toTF == nil || fromTF == nil:
+		panic(fmt.Sprintf("tofu.RegisterType: missing conversion functions for %s", t))
+	}
+	if _, ok := r[t]; ok {
+		panic(fmt.Sprintf("tofu.RegisterType: type %s is already registered", t))
+	}
+	r[t] = &TypeConverter{
+		Type:   tfType,
+		ToTF:   toTF,
+		FromTF: fromTF,
+	}
+}
+
+// Resource is implemented by the Go code to manage a resource.
+// The values it handles are of the state struct type registered along with it,
+// e.g. Create receives a Bucket and returns the Bucket with its computed fields set.
+type Resource interface {
+	// Create creates the resource planned by the configuration, and returns its state.
+	Create(plan any) (any, error)
+	// Read returns the current state of the resource, or nil if it doesn't exist anymore.
+	Read(state any) (any, error)
+	// Update updates the resource from its prior state to the plan, and returns its new state.
+	Update(prior, plan any) (any, error)
+	// Delete deletes the resource.
+	Delete(state any) error
+	// Import returns the state of the existing resource with the given ID.
+	Import(id string) (any, error)
+}
+
+// _tofu_Resource wraps values of interpreted types implementing Resource.
+type _tofu_Resource struct {
+	IValue  interface{}
+	WCreate func(plan any) (any, error)
+	WRead   func(state any) (any, error)
+	WUpdate func(prior, plan any) (any, error)
+	WDelete func(state any) error
+	WImport func(id string) (any, error)
+}
+
+func (W _tofu_Resource) Create(plan any) (any, error)        { return W.WCreate(plan) }
+func (W _tofu_Resource) Read(state any) (any, error)         { return W.WRead(state) }
+func (W _tofu_Resource) Update(prior, plan any) (any, error) { return W.WUpdate(prior, plan) }
+func (W _tofu_Resource) Delete(state any) error              { return W.WDelete(state) }
+func (W _tofu_Resource) Import(id string) (any, error)       { return W.WImport(id) }
+
+// RegisteredResource is a resource registered by the Go code.
+type RegisteredResource struct {
+	StateType reflect.Type
+	Resource  Resource
+}
+
+// ResourceRegistry holds the resources registered by the Go code, keyed by their name without the provider prefix.
+type ResourceRegistry map[string]*RegisteredResource
+
+func (r ResourceRegistry) register(name string, state any, resource Resource) {
+	_, registered := r[name]
+	r[name] = &RegisteredResource{
+		StateType: checkRegistration("tofu.RegisterResource", name, state, resource == nil, registered),
+		Resource:  resource,
+	}
+}
+
+// EphemeralResource is implemented by the Go code to open ephemeral resources, like short-lived secrets,
+// whose values are never stored in the plan or the state.
+// The values it handles are of the struct type registered along with it.
+type EphemeralResource interface {
+	// Open opens the resource described by the configuration, and returns it with its computed fields set.
+	// If renewAt isn't zero, Renew is called before then while the resource is still in use.
+	Open(config any) (result any, renewAt time.Time, err error)
+	// Renew extends the lifetime of the opened resource, and returns when to renew it next, if ever.
+	Renew(result any) (renewAt time.Time, err error)
+	// Close releases the opened resource.
+	Close(result any) error
+}
+
+// _tofu_EphemeralResource wraps values of interpreted types implementing EphemeralResource.
+type _tofu_EphemeralResource struct {
+	IValue interface{}
+	WOpen  func(config any) (any, time.Time, error)
+	WRenew func(result any) (time.Time, error)
+	WClose func(result any) error
+}
+
+func (W _tofu_EphemeralResource) Open(config any) (any, time.Time, error) { return W.WOpen(config) }
+func (W _tofu_EphemeralResource) Renew(result any) (time.Time, error)     { return W.WRenew(result) }
+func (W _tofu_EphemeralResource) Close(result any) error                  { return W.WClose(result) }
+
+// RegisteredEphemeralResource is an ephemeral resource registered by the Go code.
+type RegisteredEphemeralResource struct {
+	ResultType        reflect.Type
+	EphemeralResource EphemeralResource
+}
+
+// EphemeralResourceRegistry holds the ephemeral resources registered by the Go code, keyed by their name without the provider prefix.
+type EphemeralResourceRegistry map[string]*RegisteredEphemeralResource
+
+func (r EphemeralResourceRegistry) register(name string, result any, resource EphemeralResource) {
+	_, registered := r[name]
+	r[name] = &RegisteredEphemeralResource{
+		ResultType:        checkRegistration("tofu.RegisterEphemeralResource", name, result, resource == nil, registered),
+		EphemeralResource: resource,
+	}
+}
+
+// checkRegistration panics if the registration of a resource is invalid, and returns the type of its values otherwise.
+func checkRegistration(fn string, name string, value any, missingImpl, registered bool) reflect.Type {
+	t := reflect.TypeOf(value)
+	switch {
+	case name == "":
+		panic(fn + ": missing resource name")
+	case t == nil || t.Kind() != reflect.Struct:
+		panic(fmt.Sprintf("%s: value of %s must be a struct", fn, name))
+	case missingImpl:
+		panic(fmt.Sprintf("%s: missing implementation of %s", fn, name))
+	case registered:
+		panic(fmt.Sprintf("%s: resource %s is already registered", fn, name))
+	}
+	return t
+}
+
+// Tofu types exposed to the Go code, declared as variables so that their type is tftypes.Type.
+var (
+	tofuString  tftypes.Type = tftypes.String
+	tofuNumber  tftypes.Type = tftypes.Number
+	tofuBool    tftypes.Type = tftypes.Bool
+	tofuDynamic tftypes.Type = tftypes.DynamicPseudoType
+)
+
+// tofuSymbols returns the "tofu" package available to the Go code, which lets it customize the provider.
+//
+// Custom types are registered from an init function, e.g.:
+//
+//	func init() {
+//		tofu.RegisterType(Version{}, tofu.String,
+//			func(v any) (any, error) { return v.(Version).String(), nil },
+//			func(v any) (any, error) { return ParseVersion(v.(string)) },
+//		)
+//	}
+//
+// Resources are registered along with the struct type of their state, e.g.:
+//
+//	func init() {
+//		tofu.RegisterResource("bucket", Bucket{}, &BucketResource{})
+//		tofu.RegisterEphemeralResource("token", Token{}, &TokenResource{})
+//	}
+//
+// Non-fatal problems are reported with tofu.Warn(summary, detail), which become warning diagnostics,
+// except for functions whose warnings are only logged.
+func tofuSymbols(types TypeRegistry, resources ResourceRegistry, ephemeralResources EphemeralResourceRegistry) interp.Exports {
+	return interp.Exports{
+		"tofu/tofu": {
+			"RegisterType":              reflect.ValueOf(types.register),
+			"RegisterResource":          reflect.ValueOf(resources.register),
+			"RegisterEphemeralResource": reflect.ValueOf(ephemeralResources.register),
+			"Warn":                      reflect.ValueOf(warn),
+
+			"Resource":          reflect.ValueOf((*Resource)(nil)),
+			"EphemeralResource": reflect.ValueOf((*EphemeralResource)(nil)),
+
+			"Type":    reflect.ValueOf((*tftypes.Type)(nil)),
+			"String":  reflect.ValueOf(&tofuString).Elem(),
+			"Number":  reflect.ValueOf(&tofuNumber).Elem(),
+			"Bool":    reflect.ValueOf(&tofuBool).Elem(),
+			"Dynamic": reflect.ValueOf(&tofuDynamic).Elem(),
+			"List": reflect.ValueOf(func(elem tftypes.Type) tftypes.Type {
+				return tftypes.List{ElementType: elem}
+			}),
+			"Set": reflect.ValueOf(func(elem tftypes.Type) tftypes.Type {
+				return tftypes.Set{ElementType: elem}
+			}),
+			"Map": reflect.ValueOf(func(elem tftypes.Type) tftypes.Type {
+				return tftypes.Map{ElementType: elem}
+			}),
+			"Tuple": reflect.ValueOf(func(elems ...tftypes.Type) tftypes.Type {
+				return tftypes.Tuple{ElementTypes: elems}
+			}),
+			"Object": reflect.ValueOf(func(attrs map[string]tftypes.Type) tftypes.Type {
+				return tftypes.Object{AttributeTypes: attrs}
+			}),
+		},
+		// Yaegi looks up interface wrappers by the package path of the interface,
+		// which is the path of this package rather than "tofu".
+		reflect.TypeFor[Resource]().PkgPath() + "/main": {
+			"_Resource":          reflect.ValueOf((*_tofu_Resource)(nil)),
+			"_EphemeralResource": reflect.ValueOf((*_tofu_EphemeralResource)(nil)),
+		},
+	}
+}
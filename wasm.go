@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+const (
+	// wasmSignatureSection is the name of the custom section holding the signature manifest.
+	wasmSignatureSection = "tofu.signatures"
+	// wasmAllocExport is the export the host calls to allocate the memory holding the arguments.
+	wasmAllocExport = "tofu_alloc"
+	// wasmMemoryLimitPages caps the memory of a module instance, in 64KiB pages, i.e. 64MiB.
+	wasmMemoryLimitPages = 1024
+	// wasmCallTimeout caps the time a single call may run for, after which the instance is terminated.
+	wasmCallTimeout = 10 * time.Second
+	// wasmFuel caps the number of WebAssembly function calls a single call may make, after which the instance is terminated.
+	// wazero has no instruction metering, so fuel is consumed by function calls, and loops without calls are only
+	// bounded by wasmCallTimeout.
+	wasmFuel = 10_000_000
+	// wasmSettingsEnv is the environment variable holding the JSON-encoded settings in each instance of the module.
+	wasmSettingsEnv = "TOFU_SETTINGS"
+)
+
+// WasmBackend runs functions exported by a WebAssembly module using the wazero runtime.
+type WasmBackend struct{}
+
+func (b *WasmBackend) Name() string {
+	return "wasm"
+}
+
+func (b *WasmBackend) Description() string {
+	return "Base64-encoded WebAssembly module, with a tofu.signatures custom section describing the exported functions to become provider functions."
+}
+
+func (b *WasmBackend) Load(source string, settings cty.Value, opts ConvertOptions) (Library, []*tfprotov6.Diagnostic) {
+	binary, err := base64.StdEncoding.DecodeString(source)
+	if err != nil {
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid WebAssembly module",
+			Detail:   fmt.Sprintf("The module must be base64-encoded, e.g. with filebase64(): %s", err),
+		}}
+	}
+	settingsJSON, err := ctyjson.Marshal(settings, cty.DynamicPseudoType)
+	if err != nil {
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity:  tfprotov6.DiagnosticSeverityError,
+			Summary:   "Invalid settings",
+			Detail:    formatCtyError(err).Error(),
+			Attribute: tftypes.NewAttributePath().WithAttributeName("settings"),
+		}}
+	}
+	library, diags := LoadWasm(binary, settingsJSON)
+	if len(diags) > 0 {
+		return nil, diags
+	}
+	return library, nil
+}
+
+// WasmLibrary holds the functions of a WebAssembly module, along with the runtime running it.
+type WasmLibrary struct {
+	FunctionLibrary
+	runtime wazero.Runtime
+}
+
+// Close closes the runtime, and with it the compiled module.
+func (l *WasmLibrary) Close() error {
+	return l.runtime.Close(context.Background())
+}
+
+// LoadWasm compiles the WebAssembly module and returns the functions declared in its signature manifest.
+//
+// The manifest is a JSON object stored in the tofu.signatures custom section, using Tofu type constraints, e.g.:
+//
+//	{"hello": {"params": ["string"], "returns": "string", "description": "Greets the given name."}}
+//
+// Each function is exported with the signature (ptr, len i32) i64. It receives the JSON-encoded array of its arguments,
+// and returns the location of its JSON-encoded result packed as ptr<<32 | len.
+// The result is an object with either a "result" or an "error" field.
+// The arguments and the result use the JSON encoding of DynamicValues, i.e. dynamic values are wrapped in {"value", "type"}.
+// The host writes the arguments to memory allocated with the exported tofu_alloc(size i32) i32 function.
+//
+// Every call runs in a fresh instance of the module, with WASI but no access to the file system, the network or the host environment.
+// The JSON-encoded settings, which are dynamic values, are in the TOFU_SETTINGS environment variable of the instance.
+// Instances are limited in memory, and terminated when the call runs out of fuel or exceeds its time budget.
+func LoadWasm(module []byte, settingsJSON []byte) (*WasmLibrary, []*tfprotov6.Diagnostic) {
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(wasmMemoryLimitPages).
+		WithCloseOnContextDone(true).
+		WithCustomSections(true))
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	// The listener is set when compiling the module, and consumes the fuel of the call it's notified in.
+	compiled, err := runtime.CompileModule(experimental.WithFunctionListenerFactory(ctx, wasmFuelListener), module)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Failed to compile WebAssembly module",
+			Detail:   err.Error(),
+		}}
+	}
+
+	var manifest map[string]any
+	for _, section := range compiled.CustomSections() {
+		if section.Name() != wasmSignatureSection {
+			continue
+		}
+		if err := json.Unmarshal(section.Data(), &manifest); err != nil {
+			runtime.Close(ctx)
+			return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+				Severity: tfprotov6.DiagnosticSeverityError,
+				Summary:  "Invalid WebAssembly function signatures",
+				Detail:   err.Error(),
+			}}
+		}
+	}
+	if manifest == nil {
+		runtime.Close(ctx)
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "WebAssembly module must have a " + wasmSignatureSection + " custom section",
+		}}
+	}
+	if err := checkWasmExport(compiled, wasmAllocExport, []api.ValueType{api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}, "(size i32) i32"); err != nil {
+		runtime.Close(ctx)
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid WebAssembly module",
+			Detail:   err.Error(),
+		}}
+	}
+
+	functions := map[string]*Function{}
+	for name, signature := range manifest {
+		err := checkWasmExport(compiled, name, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI64}, "(ptr, len i32) i64")
+		if err != nil {
+			runtime.Close(ctx)
+			return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+				Severity: tfprotov6.DiagnosticSeverityError,
+				Summary:  "Invalid WebAssembly function signatures",
+				Detail:   err.Error(),
+			}}
+		}
+
+		fn, err := signatureToTFFunction(signature)
+		if err != nil {
+			runtime.Close(ctx)
+			return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+				Severity: tfprotov6.DiagnosticSeverityError,
+				Summary:  "Invalid WebAssembly function signatures",
+				Detail:   fmt.Sprintf("%s: %s", name, err),
+			}}
+		}
+
+		name := name
+		functions[name] = &Function{
+			Function: *fn,
+			Impl: func(args []*tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, *tfprotov6.FunctionError) {
+				input := []byte{'['}
+				for i, arg := range args {
+					argType, err := TFTypeToCtyType(fn.Parameters[i].Type)
+					if err != nil {
+						return nil, &tfprotov6.FunctionError{Text: err.Error()}
+					}
+					value, err := ProtoToCty(argType, arg)
+					if err == nil {
+						var encoded []byte
+						encoded, err = ctyjson.Marshal(value, argType)
+						if i > 0 {
+							input = append(input, ',')
+						}
+						input = append(input, encoded...)
+					}
+					if err != nil {
+						argument := int64(i)
+						return nil, &tfprotov6.FunctionError{
+							Text:             formatCtyError(err).Error(),
+							FunctionArgument: &argument,
+						}
+					}
+				}
+				input = append(input, ']')
+
+				output, err := callWasm(runtime, compiled, name, input, settingsJSON)
+				if err != nil {
+					return nil, &tfprotov6.FunctionError{Text: err.Error()}
+				}
+
+				var response struct {
+					Result json.RawMessage `json:"result"`
+					Error  *string         `json:"error"`
+				}
+				if err := json.Unmarshal(output, &response); err != nil {
+					return nil, &tfprotov6.FunctionError{
+						Text: fmt.Sprintf("invalid response: %s", err),
+					}
+				}
+				if response.Error != nil {
+					return nil, &tfprotov6.FunctionError{Text: *response.Error}
+				}
+
+				returnType, err := TFTypeToCtyType(fn.Return.Type)
+				if err != nil {
+					return nil, &tfprotov6.FunctionError{Text: err.Error()}
+				}
+				result := cty.NullVal(returnType)
+				if len(response.Result) > 0 {
+					result, err = ctyjson.Unmarshal(response.Result, returnType)
+					if err != nil {
+						return nil, &tfprotov6.FunctionError{
+							Text: fmt.Sprintf("invalid result: %s", formatCtyError(err)),
+						}
+					}
+				}
+				out, err := CtyToProto(returnType, result)
+				if err != nil {
+					return nil, &tfprotov6.FunctionError{Text: err.Error()}
+				}
+				return out, nil
+			},
+		}
+	}
+
+	return &WasmLibrary{FunctionLibrary: functions, runtime: runtime}, nil
+}
+
+// checkWasmExport checks that the module exports a function with the given name and signature.
+func checkWasmExport(compiled wazero.CompiledModule, name string, params, results []api.ValueType, signature string) error {
+	def, ok := compiled.ExportedFunctions()[name]
+	if !ok {
+		return fmt.Errorf("%s: the module doesn't export a function named %s", name, name)
+	}
+	if string(def.ParamTypes()) != string(params) || string(def.ResultTypes()) != string(results) {
+		return fmt.Errorf("%s: the exported function must have the signature %s", name, signature)
+	}
+	return nil
+}
+
+// callWasm calls the exported function with the given input in a fresh instance of the module, and returns its output.
+func callWasm(runtime wazero.Runtime, compiled wazero.CompiledModule, name string, input, settingsJSON []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), wasmCallTimeout)
+	defer cancel()
+	ctx, cancelFuel := context.WithCancelCause(ctx)
+	defer cancelFuel(nil)
+	ctx = context.WithValue(ctx, wasmFuelKey{}, &wasmCallFuel{remaining: wasmFuel, cancel: cancelFuel})
+
+	// Reactor modules, e.g. built by TinyGo or Rust for wasip1, are initialized through _initialize.
+	mod, err := runtime.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize").
+		WithEnv(wasmSettingsEnv, string(settingsJSON)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate the module: %w", err)
+	}
+	defer mod.Close(ctx)
+
+	results, err := mod.ExportedFunction(wasmAllocExport).Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, wasmError(ctx, err)
+	}
+	ptr := uint32(results[0])
+	if !mod.Memory().Write(ptr, input) {
+		return nil, fmt.Errorf("%s returned an out of range pointer", wasmAllocExport)
+	}
+
+	results, err = mod.ExportedFunction(name).Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return nil, wasmError(ctx, err)
+	}
+	outPtr, outLen := uint32(results[0]>>32), uint32(results[0])
+	output, ok := mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("%s returned an out of range result", name)
+	}
+	// The memory is released when the instance is closed.
+	return append([]byte(nil), output...), nil
+}
+
+func wasmError(ctx context.Context, err error) error {
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, errWasmFuel):
+		return fmt.Errorf("the function ran out of fuel after %d function calls", wasmFuel)
+	case errors.Is(cause, context.DeadlineExceeded):
+		return fmt.Errorf("the function exceeded its time limit of %s", wasmCallTimeout)
+	}
+	return err
+}
+
+// wasmFuelKey is the context key of the *wasmCallFuel of a call.
+type wasmFuelKey struct{}
+
+// wasmCallFuel is the fuel left for a call, which is cancelled once it runs out.
+// An instance runs a single call at a time, so it's only used by one goroutine.
+type wasmCallFuel struct {
+	remaining int
+	cancel    context.CancelCauseFunc
+}
+
+var errWasmFuel = errors.New("out of fuel")
+
+// wasmFuelListener consumes a unit of the fuel of the call for each function call,
+// and cancels the call when it runs out, which terminates the instance.
+var wasmFuelListener = experimental.FunctionListenerFactoryFunc(func(api.FunctionDefinition) experimental.FunctionListener {
+	return experimental.FunctionListenerFunc(func(ctx context.Context, _ api.Module, _ api.FunctionDefinition, _ []uint64, _ experimental.StackIterator) {
+		fuel, ok := ctx.Value(wasmFuelKey{}).(*wasmCallFuel)
+		if !ok {
+			return
+		}
+		fuel.remaining--
+		if fuel.remaining == 0 {
+			fuel.cancel(errWasmFuel)
+		}
+	})
+})
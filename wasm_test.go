@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// wasmProvider returns a provider configured with the module of testdata/wasm/lib.wat, and the settings.
+func wasmProvider(t *testing.T, settings cty.Value) *testProvider {
+	t.Helper()
+	module, err := os.ReadFile("testdata/wasm/lib.wasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return configuredProvider(t, map[string]cty.Value{
+		"wasm":     cty.StringVal(base64.StdEncoding.EncodeToString(module)),
+		"settings": settings,
+	})
+}
+
+func TestWasmFunctions(t *testing.T) {
+	p := wasmProvider(t, cty.ObjectVal(map[string]cty.Value{"env": cty.StringVal("prod")}))
+
+	requireEqual(t, p.mustCall("echo", cty.StringVal("papaya")), cty.StringVal("papaya"))
+	requireEqual(t, p.mustCall("settings"), cty.ObjectVal(map[string]cty.Value{"env": cty.StringVal("prod")}))
+
+	tests := map[string]string{
+		"fail": "failed",
+		"spin": "the function ran out of fuel after 10000000 function calls",
+	}
+	for name, text := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, funcErr := p.call(name)
+			if funcErr == nil || funcErr.Text != text {
+				t.Fatalf("got error %v, want %q", funcErr, text)
+			}
+		})
+	}
+}
+
+func TestWasmReconfigure(t *testing.T) {
+	p := wasmProvider(t, cty.NullVal(cty.DynamicPseudoType))
+	library := p.server.(*FunctionProvider).libraries[0].(*WasmLibrary)
+	requireEqual(t, p.mustCall("settings"), cty.NullVal(cty.DynamicPseudoType))
+
+	requireNoErrors(t, p.configure(map[string]cty.Value{"go": cty.StringVal("package lib\nfunc Hello() string { return \"hello\" }")}))
+	// The runtime of the previous module is closed, so it can't instantiate it anymore.
+	if _, err := callWasm(library.runtime, nil, "echo", nil, nil); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Fatalf("got error %v, want the runtime to be closed", err)
+	}
+}
+
+func TestWasmDiagnostics(t *testing.T) {
+	tests := []struct {
+		name    string
+		module  string
+		summary string
+		detail  string
+	}{
+		{name: "not base64", module: "not base64!", summary: "Invalid WebAssembly module", detail: "must be base64-encoded"},
+		{name: "not a module", module: base64.StdEncoding.EncodeToString([]byte("papaya")), summary: "Failed to compile WebAssembly module"},
+		{
+			name:    "no signatures",
+			module:  base64.StdEncoding.EncodeToString([]byte("\x00asm\x01\x00\x00\x00")),
+			summary: "WebAssembly module must have a tofu.signatures custom section",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diags := startProvider(t, "").configure(map[string]cty.Value{"wasm": cty.StringVal(test.module)})
+			requireDiagnostic(t, diags, tfprotov6.DiagnosticSeverityError, test.summary, test.detail)
+		})
+	}
+}
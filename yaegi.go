@@ -0,0 +1,749 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/traefik/yaegi/interp"
+	"github.com/traefik/yaegi/stdlib"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// YaegiBackend runs Go functions using the Yaegi interpreter.
+type YaegiBackend struct{}
+
+func (b *YaegiBackend) Name() string {
+	return "go"
+}
+
+func (b *YaegiBackend) Description() string {
+	return "Go source code of the lib package, whose exported functions become provider functions. " +
+		"Data sources and resources are ignored here, as they must be declared in the Go file given by the " + libraryEnv + " environment variable."
+}
+
+// Load loads the functions of the Go code, with a warning if it declares data sources or (ephemeral) resources,
+// which Tofu needs to know before configuring the provider.
+func (b *YaegiBackend) Load(source string, settings cty.Value, opts ConvertOptions) (Library, []*tfprotov6.Diagnostic) {
+	library, diags := LoadGo(source, opts)
+	if len(diags) > 0 {
+		return nil, diags
+	}
+	if diags := library.Init(settings); len(diags) > 0 {
+		return nil, diags
+	}
+
+	var ignored []string
+	for name := range library.DataSources {
+		ignored = append(ignored, "data source "+name)
+	}
+	for name := range library.Resources {
+		ignored = append(ignored, "resource "+name)
+	}
+	for name := range library.EphemeralResources {
+		ignored = append(ignored, "ephemeral resource "+name)
+	}
+	if len(ignored) > 0 {
+		slices.Sort(ignored)
+		diags = append(diags, &tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityWarning,
+			Summary:  "Data sources and resources are ignored",
+			Detail: fmt.Sprintf("The Go code declares the %s, which are ignored as Tofu needs to know them before configuring the provider. "+
+				"Declare them in the Go file given by the %s environment variable instead.", strings.Join(ignored, ", "), libraryEnv),
+		})
+	}
+	return FunctionLibrary(library.Functions), diags
+}
+
+// goSourceName is the file name of the Go code in diagnostics, as the package must be lib.
+const goSourceName = "lib.go"
+
+// Validate parses, type-checks and compiles the Go code without running it.
+// Syntax errors are reported once per line like the Go compiler does, and type errors once each.
+// Type errors the interpreter accepts, like unused variables, are reported as warnings.
+func (b *YaegiBackend) Validate(source string) []*tfprotov6.Diagnostic {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, goSourceName, source, 0)
+	if err != nil {
+		var errs scanner.ErrorList
+		if !errors.As(err, &errs) {
+			return []*tfprotov6.Diagnostic{goSourceDiagnostic(err.Error())}
+		}
+		var diags []*tfprotov6.Diagnostic
+		for _, err := range errs {
+			diags = append(diags, goSourceDiagnostic(err.Error()))
+		}
+		return diags
+	}
+
+	var diags []*tfprotov6.Diagnostic
+	for _, err := range checkGoTypes(fset, file) {
+		diag := goSourceDiagnostic(err.Error())
+		if err.Soft {
+			diag.Severity = tfprotov6.DiagnosticSeverityWarning
+		}
+		diags = append(diags, diag)
+	}
+	if hasErrors(diags) {
+		return diags
+	}
+
+	// The interpreter doesn't support all of Go, e.g. some generic functions of the standard library.
+	interpreter, interpDiags := newGoInterpreter(interp.Options{}, TypeRegistry{}, ResourceRegistry{}, EphemeralResourceRegistry{})
+	if len(interpDiags) > 0 {
+		return append(diags, interpDiags...)
+	}
+	if err := recoverGo(func() error { _, err := interpreter.Compile(source); return err }); err != nil {
+		// The interpreter reports positions as line:column, without a file name.
+		msg := err.Error()
+		if compilePosition.MatchString(msg) {
+			msg = goSourceName + ":" + msg
+		}
+		diags = append(diags, goSourceDiagnostic(msg))
+	}
+	return diags
+}
+
+// recoverGo returns the error of compiling or evaluating Go code,
+// including the panics of the interpreter on some invalid code, e.g. using a package without importing it.
+func recoverGo(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return f()
+}
+
+var compilePosition = regexp.MustCompile(`^\d+:\d+: `)
+
+func goSourceDiagnostic(detail string) *tfprotov6.Diagnostic {
+	return &tfprotov6.Diagnostic{
+		Severity: tfprotov6.DiagnosticSeverityError,
+		Summary:  "Invalid Go code",
+		Detail:   detail,
+	}
+}
+
+// GoLibrary holds what the Go code of the lib package exports, by Tofu-facing name.
+type GoLibrary struct {
+	Functions          map[string]*Function
+	DataSources        map[string]*DataSource
+	Resources          map[string]*ManagedResource
+	EphemeralResources map[string]*ManagedEphemeralResource
+
+	init reflect.Value
+	opts ConvertOptions
+}
+
+// newGoInterpreter returns an interpreter of Go code, with the standard library and the tofu and tofu/log packages.
+func newGoInterpreter(options interp.Options, types TypeRegistry, resources ResourceRegistry, ephemeralResources EphemeralResourceRegistry) (*interp.Interpreter, []*tfprotov6.Diagnostic) {
+	interpreter := interp.New(options)
+	if err := interpreter.Use(stdlib.Symbols); err != nil {
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Failed to load Go standard library",
+			Detail:   err.Error(),
+		}}
+	}
+	if err := interpreter.Use(tofuSymbols(types, resources, ephemeralResources)); err != nil {
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Failed to load tofu package",
+			Detail:   err.Error(),
+		}}
+	}
+	if err := interpreter.Use(logSymbols()); err != nil {
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Failed to load log package",
+			Detail:   err.Error(),
+		}}
+	}
+	return interpreter, nil
+}
+
+// LoadGo evaluates the Go code of the lib package.
+// Exported functions named DataFoo become the data source go_foo, and all others but Init become functions.
+// Resources registered as foo with tofu.RegisterResource or tofu.RegisterEphemeralResource
+// become the resource or ephemeral resource go_foo.
+func LoadGo(code string, opts ConvertOptions) (*GoLibrary, []*tfprotov6.Diagnostic) {
+	opts.Types = TypeRegistry{}
+	resources := ResourceRegistry{}
+	ephemeralResources := EphemeralResourceRegistry{}
+	interpreter, diags := newGoInterpreter(interp.Options{}, opts.Types, resources, ephemeralResources)
+	if len(diags) > 0 {
+		return nil, diags
+	}
+
+	err := recoverGo(func() error { _, err := interpreter.Eval(code); return err })
+	if err != nil {
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Failed to evaluate Go code",
+			Detail:   err.Error(),
+		}}
+	}
+
+	docs := goFuncDocs(code)
+	exports := interpreter.Symbols("lib")
+	libExports := exports["lib"]
+	// Evaluated after looking up the exports, as it adds conversion functions to them.
+	textTypes, err := goTextTypes(interpreter, code)
+	if err != nil {
+		return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid text type",
+			Detail:   err.Error(),
+		}}
+	}
+
+	library := &GoLibrary{
+		Functions:          map[string]*Function{},
+		DataSources:        map[string]*DataSource{},
+		Resources:          map[string]*ManagedResource{},
+		EphemeralResources: map[string]*ManagedEphemeralResource{},
+		opts:               opts,
+	}
+	for name, export := range libExports {
+		if export.Kind() != reflect.Func {
+			continue
+		}
+		if name == "Init" {
+			if diags := checkGoInit(export.Type(), opts); len(diags) > 0 {
+				return nil, diags
+			}
+			library.init = export
+			continue
+		}
+		if dataSourceName, ok := cutExportPrefix(name, "Data"); ok {
+			dataSource, err := GoFunctionToDataSource(export, opts)
+			if err != nil {
+				return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+					Severity: tfprotov6.DiagnosticSeverityError,
+					Summary:  "Invalid data source",
+					Detail:   fmt.Sprintf("%s: %s", name, err),
+				}}
+			}
+			library.DataSources[providerTypeName+"_"+GoNameToTFName(dataSourceName)] = dataSource
+			continue
+		}
+		if decl := textTypes.funcs[name]; decl != nil {
+			export = textTypes.wrap(export, decl)
+		}
+		fn, diags := GoFunctionToTFFunction(interpreter, export, opts)
+		if len(diags) > 0 {
+			return nil, diags
+		}
+		if doc := docs[name]; doc != nil {
+			fn.Description = doc.Description
+			fn.DeprecationMessage = doc.Deprecation
+			if len(doc.Params) == len(fn.Parameters) {
+				for i, param := range doc.Params {
+					fn.Parameters[i].Name = param
+				}
+			}
+		}
+		library.Functions[GoNameToTFName(name)] = fn
+	}
+	for name, registered := range resources {
+		resource, err := NewManagedResource(registered, opts)
+		if err != nil {
+			return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+				Severity: tfprotov6.DiagnosticSeverityError,
+				Summary:  "Invalid resource",
+				Detail:   fmt.Sprintf("%s: %s", name, err),
+			}}
+		}
+		library.Resources[providerTypeName+"_"+name] = resource
+	}
+	for name, registered := range ephemeralResources {
+		resource, err := NewManagedEphemeralResource(registered, opts)
+		if err != nil {
+			return nil, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+				Severity: tfprotov6.DiagnosticSeverityError,
+				Summary:  "Invalid ephemeral resource",
+				Detail:   fmt.Sprintf("%s: %s", name, err),
+			}}
+		}
+		library.EphemeralResources[providerTypeName+"_"+name] = resource
+	}
+
+	return library, nil
+}
+
+// checkGoInit checks the signature of the Init function of the Go code, and that its settings can be converted.
+func checkGoInit(fnType reflect.Type, opts ConvertOptions) []*tfprotov6.Diagnostic {
+	if fnType.NumIn() != 1 || fnType.NumOut() != 1 || fnType.Out(0) != reflect.TypeFor[error]() {
+		return []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid Init function",
+			Detail:   "Init must have the signature func(settings T) error.",
+		}}
+	}
+	_, err := GoTypeToTFType(fnType.In(0), opts)
+	if err == nil {
+		err = checkText(fnType.In(0), textUnmarshalerType, opts)
+	}
+	if err != nil {
+		return []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity: tfprotov6.DiagnosticSeverityError,
+			Summary:  "Invalid Init function",
+			Detail:   fmt.Sprintf("settings: %s", err),
+		}}
+	}
+	return nil
+}
+
+// Init calls the Init function of the Go code, if any, with the settings converted to the type of its parameter.
+func (l *GoLibrary) Init(settings cty.Value) []*tfprotov6.Diagnostic {
+	if !l.init.IsValid() {
+		return nil
+	}
+	arg, diags := l.convertSettings(settings)
+	if len(diags) > 0 {
+		return diags
+	}
+	if err, _ := l.init.Call([]reflect.Value{arg})[0].Interface().(error); err != nil {
+		return []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity:  tfprotov6.DiagnosticSeverityError,
+			Summary:   "Failed to initialize Go code",
+			Detail:    err.Error(),
+			Attribute: tftypes.NewAttributePath().WithAttributeName("settings"),
+		}}
+	}
+	return nil
+}
+
+// ValidateSettings checks that the settings can be passed to the Init function of the Go code, if any,
+// without calling it. Settings which aren't wholly known yet are checked when configuring the provider.
+func (l *GoLibrary) ValidateSettings(settings cty.Value) []*tfprotov6.Diagnostic {
+	if !l.init.IsValid() || !settings.IsWhollyKnown() {
+		return nil
+	}
+	_, diags := l.convertSettings(settings)
+	return diags
+}
+
+// convertSettings converts the settings to the type of the parameter of the Init function.
+// The settings may only be null if that type is a pointer.
+func (l *GoLibrary) convertSettings(settings cty.Value) (reflect.Value, []*tfprotov6.Diagnostic) {
+	settingsPath := tftypes.NewAttributePath().WithAttributeName("settings")
+	goType := l.init.Type().In(0)
+	if settings.IsNull() && goType.Kind() != reflect.Ptr {
+		return reflect.Value{}, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity:  tfprotov6.DiagnosticSeverityError,
+			Summary:   "Missing settings",
+			Detail:    "The Init function requires the settings attribute to be set.",
+			Attribute: settingsPath,
+		}}
+	}
+	goSettings, err := ctyToTypedGo(goType, settings, l.opts)
+	if err != nil {
+		return reflect.Value{}, []*tfprotov6.Diagnostic{&tfprotov6.Diagnostic{
+			Severity:  tfprotov6.DiagnosticSeverityError,
+			Summary:   "Invalid settings",
+			Detail:    err.Error(),
+			Attribute: settingsPath,
+		}}
+	}
+	arg := reflect.ValueOf(goSettings)
+	if !arg.IsValid() {
+		arg = reflect.Zero(goType)
+	}
+	return arg, nil
+}
+
+// ctyToTypedGo converts a value of any type to the Go type, converting it to the Tofu type of the Go type first.
+func ctyToTypedGo(goType reflect.Type, value cty.Value, opts ConvertOptions) (any, error) {
+	tfType, err := GoTypeToTFType(goType, opts)
+	if err != nil {
+		return nil, err
+	}
+	ctyType, err := TFTypeToCtyType(tfType)
+	if err != nil {
+		return nil, err
+	}
+	converted, err := convert.Convert(value, ctyType)
+	if err != nil {
+		return nil, formatCtyError(err)
+	}
+	goValue, err := CtyToGo(goType, converted, opts)
+	if err != nil {
+		return nil, formatCtyError(err)
+	}
+	return goValue, nil
+}
+
+// goTextTypeSet holds the types declared in the Go code with MarshalText and UnmarshalText methods, like enums.
+// The interpreter represents values of such types as values of their underlying type, without methods,
+// so isText can't detect them: instead, the exported functions using them as the type of a parameter or of the result,
+// or a pointer to it, are wrapped into functions converting these values to and from strings using the methods.
+// Other uses of these types, like in struct fields, are rejected by checkNested.
+type goTextTypeSet struct {
+	types map[string]*goTextType
+	// funcs are the declarations of the exported functions using the types.
+	funcs map[string]*ast.FuncDecl
+}
+
+// goTextType converts values of a type declared in the Go code to and from strings.
+type goTextType struct {
+	// unmarshal is a func(string) (T, error) and marshal a func(T) (string, error), calling the methods of T.
+	unmarshal, marshal reflect.Value
+}
+
+// goTextTypes evaluates the conversions of the types declared in the Go code with MarshalText and UnmarshalText methods.
+func goTextTypes(interpreter *interp.Interpreter, code string) (*goTextTypeSet, error) {
+	set := &goTextTypeSet{types: map[string]*goTextType{}, funcs: map[string]*ast.FuncDecl{}}
+	file, err := parser.ParseFile(token.NewFileSet(), goSourceName, code, 0)
+	if err != nil {
+		// The code was evaluated, so this doesn't happen.
+		return set, nil
+	}
+
+	methods := map[string]map[string]bool{}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || len(fn.Recv.List) != 1 {
+			continue
+		}
+		recv := fn.Recv.List[0].Type
+		if star, ok := recv.(*ast.StarExpr); ok {
+			recv = star.X
+		}
+		if ident, ok := recv.(*ast.Ident); ok {
+			if methods[ident.Name] == nil {
+				methods[ident.Name] = map[string]bool{}
+			}
+			methods[ident.Name][fn.Name.Name] = true
+		}
+	}
+
+	var names []string
+	var conversions strings.Builder
+	conversions.WriteString("package lib\n")
+	for name, typeMethods := range methods {
+		if !typeMethods["MarshalText"] || !typeMethods["UnmarshalText"] {
+			continue
+		}
+		names = append(names, name)
+		// The variables are exported so that they can be looked up.
+		fmt.Fprintf(&conversions, "var TofuUnmarshalText_%[1]s = func(s string) (%[1]s, error) { var v %[1]s; err := v.UnmarshalText([]byte(s)); return v, err }\n", name)
+		fmt.Fprintf(&conversions, "var TofuMarshalText_%[1]s = func(v %[1]s) (string, error) { text, err := v.MarshalText(); return string(text), err }\n", name)
+	}
+	if len(names) == 0 {
+		return set, nil
+	}
+	for _, name := range names {
+		set.types[name] = &goTextType{}
+	}
+	if err := set.checkNested(file); err != nil {
+		return nil, err
+	}
+	if err := recoverGo(func() error { _, err := interpreter.Eval(conversions.String()); return err }); err != nil {
+		return nil, fmt.Errorf("text methods: %w", err)
+	}
+	exports := interpreter.Symbols("lib")["lib"]
+	for _, name := range names {
+		set.types[name].unmarshal = exports["TofuUnmarshalText_"+name]
+		set.types[name].marshal = exports["TofuMarshalText_"+name]
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !fn.Name.IsExported() {
+			continue
+		}
+		fields := fn.Type.Params.List
+		if fn.Type.Results != nil {
+			fields = append(slices.Clip(fields), fn.Type.Results.List...)
+		}
+		for _, field := range fields {
+			if textType, _ := set.typeOf(field.Type); textType != nil {
+				set.funcs[fn.Name.Name] = fn
+				break
+			}
+		}
+	}
+	return set, nil
+}
+
+// checkNested returns an error if a text type is used by the exported functions or the registered (ephemeral) resources
+// other than as the type of a parameter or of the result of a function, or a pointer to it,
+// e.g. in a struct field or as the element type of a slice, where it would be converted as its underlying type.
+func (s *goTextTypeSet) checkNested(file *ast.File) error {
+	declared := map[string]ast.Expr{}
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok {
+			for _, spec := range gen.Specs {
+				if typeSpec, ok := spec.(*ast.TypeSpec); ok {
+					declared[typeSpec.Name.Name] = typeSpec.Type
+				}
+			}
+		}
+	}
+	var errs []error
+	check := func(user string, expr ast.Expr) bool {
+		name := s.nested(expr, declared, map[string]bool{})
+		if name != "" {
+			errs = append(errs, fmt.Errorf("%s: the text type %s is only supported as the type of a function parameter or result, or a pointer to it", user, name))
+		}
+		return name != ""
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !fn.Name.IsExported() {
+			continue
+		}
+		fields := fn.Type.Params.List
+		if fn.Type.Results != nil {
+			fields = append(slices.Clip(fields), fn.Type.Results.List...)
+		}
+		// Only the parameters and results of functions are wrapped, not those of Init and data sources.
+		_, dataSource := cutExportPrefix(fn.Name.Name, "Data")
+		wrapped := fn.Name.Name != "Init" && !dataSource
+		for _, field := range fields {
+			if textType, _ := s.typeOf(field.Type); textType != nil && wrapped {
+				continue
+			}
+			if check(fn.Name.Name, field.Type) {
+				break
+			}
+		}
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) < 2 {
+			return true
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || selector.Sel.Name != "RegisterResource" && selector.Sel.Name != "RegisterEphemeralResource" {
+			return true
+		}
+		if lit, ok := call.Args[1].(*ast.CompositeLit); ok && lit.Type != nil {
+			check(selector.Sel.Name, lit.Type)
+		}
+		return true
+	})
+	return errors.Join(errs...)
+}
+
+// nested returns the name of a text type used in the type expression, following the types declared in the file
+// which aren't visited yet.
+func (s *goTextTypeSet) nested(expr ast.Expr, declared map[string]ast.Expr, visited map[string]bool) string {
+	var found string
+	ast.Inspect(expr, func(node ast.Node) bool {
+		if found != "" {
+			return false
+		}
+		switch node := node.(type) {
+		case *ast.Field:
+			// Skips the names of struct fields and parameters.
+			found = s.nested(node.Type, declared, visited)
+			return false
+		case *ast.SelectorExpr:
+			// A type of another package.
+			return false
+		case *ast.Ident:
+			if s.types[node.Name] != nil {
+				found = node.Name
+			} else if decl, ok := declared[node.Name]; ok && !visited[node.Name] {
+				visited[node.Name] = true
+				found = s.nested(decl, declared, visited)
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// typeOf returns the text type of a type expression, if it's one of the types or a pointer to it.
+func (s *goTextTypeSet) typeOf(expr ast.Expr) (textType *goTextType, ptr bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr, ptr = star.X, true
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return s.types[ident.Name], ptr
+	}
+	return nil, false
+}
+
+// wrap returns a function like fn, with string parameters and result instead of those of the text types,
+// and an error result which is an *argumentError if an argument can't be converted.
+func (s *goTextTypeSet) wrap(fn reflect.Value, decl *ast.FuncDecl) reflect.Value {
+	fnType := fn.Type()
+	if fnType.NumOut() == 0 || fnType.NumOut() > 2 || fnType.NumOut() == 2 && fnType.Out(1) != reflect.TypeFor[error]() {
+		// Reported by GoFunctionToTFFunction.
+		return fn
+	}
+	stringType := reflect.TypeFor[string]()
+
+	var params []*goTextType
+	var in []reflect.Type
+	for _, field := range decl.Type.Params.List {
+		textType, ptr := s.typeOf(field.Type)
+		for range max(len(field.Names), 1) {
+			params = append(params, textType)
+			switch {
+			case textType == nil:
+				in = append(in, fnType.In(len(in)))
+			case ptr:
+				in = append(in, reflect.PointerTo(stringType))
+			default:
+				in = append(in, stringType)
+			}
+		}
+	}
+	result, resultPtr := s.typeOf(decl.Type.Results.List[0].Type)
+	out := []reflect.Type{fnType.Out(0), reflect.TypeFor[error]()}
+	switch {
+	case result != nil && resultPtr:
+		out[0] = reflect.PointerTo(stringType)
+	case result != nil:
+		out[0] = stringType
+	}
+
+	wrapperType := reflect.FuncOf(in, out, fnType.IsVariadic())
+	return reflect.MakeFunc(wrapperType, func(args []reflect.Value) []reflect.Value {
+		fail := func(err error) []reflect.Value {
+			return []reflect.Value{reflect.Zero(out[0]), reflect.ValueOf(&err).Elem()}
+		}
+		for i, textType := range params {
+			if textType == nil {
+				continue
+			}
+			arg, err := textType.fromString(args[i], fnType.In(i))
+			if err != nil {
+				return fail(&argumentError{argument: i, err: err})
+			}
+			args[i] = arg
+		}
+		var results []reflect.Value
+		if fnType.IsVariadic() {
+			results = fn.CallSlice(args)
+		} else {
+			results = fn.Call(args)
+		}
+		if len(results) == 2 && !results[1].IsNil() {
+			return fail(results[1].Interface().(error))
+		}
+		if result == nil {
+			return []reflect.Value{results[0], reflect.Zero(out[1])}
+		}
+		text, err := result.toString(results[0], out[0])
+		if err != nil {
+			return fail(err)
+		}
+		return []reflect.Value{text, reflect.Zero(out[1])}
+	})
+}
+
+// fromString converts a string, or a pointer to it, to a value of goType, the text type or a pointer to it.
+func (t *goTextType) fromString(value reflect.Value, goType reflect.Type) (reflect.Value, error) {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return reflect.Zero(goType), nil
+		}
+		elem, err := t.fromString(value.Elem(), goType.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(goType.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	}
+	results := t.unmarshal.Call([]reflect.Value{value})
+	if !results[1].IsNil() {
+		return reflect.Value{}, results[1].Interface().(error)
+	}
+	return results[0].Convert(goType), nil
+}
+
+// toString converts a value of the text type, or a pointer to it, to a value of type stringType, a string or a pointer to it.
+func (t *goTextType) toString(value reflect.Value, stringType reflect.Type) (reflect.Value, error) {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return reflect.Zero(stringType), nil
+		}
+		text, err := t.toString(value.Elem(), stringType.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(stringType.Elem())
+		ptr.Elem().Set(text)
+		return ptr, nil
+	}
+	results := t.marshal.Call([]reflect.Value{value.Convert(t.marshal.Type().In(0))})
+	if !results[1].IsNil() {
+		return reflect.Value{}, results[1].Interface().(error)
+	}
+	return results[0], nil
+}
+
+// goFuncDoc is the documentation of a function of the Go code.
+type goFuncDoc struct {
+	// Description is the doc comment, without its deprecation paragraph.
+	Description string
+	// Deprecation is the deprecation paragraph of the doc comment without its "Deprecated: " prefix, if any.
+	Deprecation string
+	// Params are the names of the parameters, empty if unnamed.
+	Params []string
+}
+
+// goFuncDocs returns the documentation of the top-level functions of the Go code, by name.
+// As usual in Go, a function is deprecated by a paragraph of its doc comment starting with "Deprecated: ".
+func goFuncDocs(code string) map[string]*goFuncDoc {
+	docs := map[string]*goFuncDoc{}
+	file, err := parser.ParseFile(token.NewFileSet(), goSourceName, code, parser.ParseComments)
+	if err != nil {
+		// The code was evaluated, so this doesn't happen.
+		return docs
+	}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil {
+			continue
+		}
+		doc := &goFuncDoc{}
+		for _, field := range fn.Type.Params.List {
+			if len(field.Names) == 0 {
+				doc.Params = append(doc.Params, "")
+			}
+			for _, name := range field.Names {
+				doc.Params = append(doc.Params, name.Name)
+			}
+		}
+		var paragraphs []string
+		for _, paragraph := range strings.Split(fn.Doc.Text(), "\n\n") {
+			if message, ok := strings.CutPrefix(paragraph, "Deprecated: "); ok {
+				doc.Deprecation = strings.Join(strings.Fields(message), " ")
+			} else if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+				paragraphs = append(paragraphs, paragraph)
+			}
+		}
+		doc.Description = strings.Join(paragraphs, "\n\n")
+		docs[fn.Name.Name] = doc
+	}
+	return docs
+}
+
+// cutExportPrefix returns the name without the prefix, if the name starts with the prefix followed by an exported name.
+func cutExportPrefix(name, prefix string) (string, bool) {
+	rest, ok := strings.CutPrefix(name, prefix)
+	if !ok || rest == "" || !unicode.IsUpper([]rune(rest)[0]) {
+		return "", false
+	}
+	return rest, true
+}